@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"task_manager/customError"
+	"task_manager/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims is the payload of the access tokens issued on login.
+type Claims struct {
+	UserID   int         `json:"uid"`
+	Username string      `json:"username"`
+	Role     models.Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+func (c *Claims) IsAdmin() bool {
+	return c.Role == models.RoleAdmin
+}
+
+// TokenService signs and verifies HS256 access tokens.
+type TokenService struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewTokenService(secret string, ttl time.Duration) (*TokenService, error) {
+	if len(secret) < 32 {
+		return nil, errors.New("JWT secret must be at least 32 characters long")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("JWT lifetime must be positive")
+	}
+	return &TokenService{secret: []byte(secret), ttl: ttl}, nil
+}
+
+func (s *TokenService) Issue(user models.User) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   fmt.Sprint(user.ID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+}
+
+// Parse verifies the signature and expiry of a token and returns its claims.
+func (s *TokenService) Parse(token string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, &customError.UnauthorizedError{Reason: "Invalid or expired token"}
+	}
+	return claims, nil
+}
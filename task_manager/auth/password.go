@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"task_manager/customError"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
+
+func HashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", passwordError("must be at least 8 characters long")
+	}
+	// bcrypt silently ignores everything past 72 bytes, so refuse instead
+	if len(password) > 72 {
+		return "", passwordError("must be at most 72 bytes long")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func passwordError(reason string) error {
+	return &customError.ValidationError{Fields: []customError.FieldError{{Field: "password", Reason: reason}}}
+}
+
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
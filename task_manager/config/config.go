@@ -0,0 +1,497 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"mime"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	MongoBackend  = "mongo"
+	MemoryBackend = "memory"
+)
+
+// Attachment backends, where the bytes of attached files are kept.
+const (
+	FilesystemBlobs = "filesystem"
+	GridFSBlobs     = "gridfs"
+)
+
+// Reminder channels, the ways reminders are sent.
+const (
+	LogChannel     = "log"
+	WebhookChannel = "webhook"
+	EmailChannel   = "email"
+)
+
+// Config holds every setting of the server. Load fills it from, in order
+// of precedence, command line flags, environment variables, an optional
+// YAML or TOML file and the built-in defaults.
+type Config struct {
+	Addr     string
+	LogLevel slog.Level
+	Storage  Storage
+	Timeouts Timeouts
+	Auth     Auth
+	// WorkflowFile optionally replaces the default status workflow.
+	WorkflowFile string
+	Trash        Trash
+	Attachments  Attachments
+	Recurrence   Recurrence
+	Reminders    Reminders
+}
+
+type Storage struct {
+	Backend string
+	Mongo   Mongo
+	// Seed inserts a sample task into an empty store on startup.
+	Seed bool
+	// FixturesFile names a JSON or YAML list of tasks loaded on startup.
+	FixturesFile string
+}
+
+type Mongo struct {
+	URI             string
+	Database        string
+	TasksCollection string
+}
+
+type Timeouts struct {
+	DBRead   time.Duration
+	DBWrite  time.Duration
+	Shutdown time.Duration
+}
+
+type Auth struct {
+	JWTSecret string
+	JWTTTL    time.Duration
+	// AdminUsername and AdminPassword name an admin account created on
+	// startup if it does not exist yet.
+	AdminUsername string
+	AdminPassword string
+}
+
+type Trash struct {
+	Retention     time.Duration
+	PurgeInterval time.Duration
+}
+
+type Attachments struct {
+	// Backend is FilesystemBlobs or GridFSBlobs; GridFS needs the mongo
+	// storage backend.
+	Backend string
+	// Dir is where the filesystem backend writes files.
+	Dir     string
+	MaxSize int64
+	// AllowedTypes lists the accepted media types, such as "image/png" or
+	// "image/*"; empty accepts any type.
+	AllowedTypes []string
+}
+
+type Recurrence struct {
+	// Horizon is how far ahead of their due date occurrences of recurring
+	// tasks are created.
+	Horizon  time.Duration
+	Interval time.Duration
+}
+
+type Reminders struct {
+	Interval time.Duration
+	// MaxDelay is how late a reminder may still be sent, for instance
+	// after downtime; older ones are dropped.
+	MaxDelay    time.Duration
+	MaxAttempts int
+	// Channels lists the enabled channels: LogChannel, WebhookChannel
+	// and EmailChannel.
+	Channels      []string
+	WebhookURL    string
+	WebhookSecret string
+	SMTPAddr      string
+	SMTPUsername  string
+	SMTPPassword  string
+	SMTPFrom      string
+}
+
+// setting describes one configuration value: its key in the config file,
+// its environment variable, its flag (empty for secrets, which should not
+// show up in process listings) and its default.
+type setting struct {
+	key, env, flag, def, usage string
+	// field points into the Config the value is stored in
+	field func(c *Config) any
+	// positive rejects zero durations and sizes
+	positive bool
+}
+
+var settings = []setting{
+	{key: "addr", env: "ADDR", flag: "addr", def: "localhost:3000", usage: "address to listen on",
+		field: func(c *Config) any { return &c.Addr }},
+	{key: "log_level", env: "LOG_LEVEL", flag: "log-level", def: "info", usage: "debug, info, warn or error",
+		field: func(c *Config) any { return &c.LogLevel }},
+	{key: "storage.backend", env: "STORAGE_BACKEND", flag: "storage", def: MongoBackend, usage: "mongo or memory",
+		field: func(c *Config) any { return &c.Storage.Backend }},
+	{key: "storage.seed", env: "SEED_DATA", flag: "seed", def: "false", usage: "insert a sample task into an empty store",
+		field: func(c *Config) any { return &c.Storage.Seed }},
+	{key: "storage.fixtures_file", env: "FIXTURES_FILE", flag: "fixtures", usage: "JSON or YAML file of tasks to load on startup",
+		field: func(c *Config) any { return &c.Storage.FixturesFile }},
+	{key: "mongo.uri", env: "MONGODB_URI", flag: "mongo-uri", def: "mongodb://localhost:27017", usage: "MongoDB connection string",
+		field: func(c *Config) any { return &c.Storage.Mongo.URI }},
+	{key: "mongo.database", env: "DB_NAME", flag: "db-name", def: "taskmanager", usage: "MongoDB database",
+		field: func(c *Config) any { return &c.Storage.Mongo.Database }},
+	{key: "mongo.tasks_collection", env: "TASKS_COLLECTION", flag: "tasks-collection", def: "tasks", usage: "MongoDB collection holding the tasks",
+		field: func(c *Config) any { return &c.Storage.Mongo.TasksCollection }},
+	{key: "timeouts.db_read", env: "DB_READ_TIMEOUT", flag: "db-read-timeout", def: "5s", usage: "upper bound for a database read, 0 for none",
+		field: func(c *Config) any { return &c.Timeouts.DBRead }},
+	{key: "timeouts.db_write", env: "DB_WRITE_TIMEOUT", flag: "db-write-timeout", def: "10s", usage: "upper bound for a database write, 0 for none",
+		field: func(c *Config) any { return &c.Timeouts.DBWrite }},
+	{key: "timeouts.shutdown", env: "SHUTDOWN_TIMEOUT", flag: "shutdown-timeout", def: "15s", usage: "how long to wait for in-flight requests when stopping",
+		field: func(c *Config) any { return &c.Timeouts.Shutdown }, positive: true},
+	{key: "auth.jwt_secret", env: "JWT_SECRET", usage: "secret signing access tokens",
+		field: func(c *Config) any { return &c.Auth.JWTSecret }},
+	{key: "auth.jwt_ttl", env: "JWT_TTL", flag: "jwt-ttl", def: "24h", usage: "lifetime of access tokens",
+		field: func(c *Config) any { return &c.Auth.JWTTTL }, positive: true},
+	{key: "auth.admin_username", env: "ADMIN_USERNAME", usage: "admin account created on startup",
+		field: func(c *Config) any { return &c.Auth.AdminUsername }},
+	{key: "auth.admin_password", env: "ADMIN_PASSWORD", usage: "password of the startup admin account",
+		field: func(c *Config) any { return &c.Auth.AdminPassword }},
+	{key: "workflow_file", env: "WORKFLOW_FILE", flag: "workflow-file", usage: "JSON file replacing the default status workflow",
+		field: func(c *Config) any { return &c.WorkflowFile }},
+	{key: "trash.retention", env: "TRASH_RETENTION", flag: "trash-retention", def: "720h", usage: "how long deleted tasks stay in the trash",
+		field: func(c *Config) any { return &c.Trash.Retention }, positive: true},
+	{key: "trash.purge_interval", env: "TRASH_PURGE_INTERVAL", flag: "trash-purge-interval", def: "1h", usage: "how often expired tasks are purged",
+		field: func(c *Config) any { return &c.Trash.PurgeInterval }, positive: true},
+	{key: "attachments.backend", env: "ATTACHMENTS_BACKEND", flag: "attachments-backend", def: FilesystemBlobs, usage: "filesystem or gridfs",
+		field: func(c *Config) any { return &c.Attachments.Backend }},
+	{key: "attachments.dir", env: "ATTACHMENTS_DIR", flag: "attachments-dir", def: "attachments", usage: "directory the filesystem backend stores attachments in",
+		field: func(c *Config) any { return &c.Attachments.Dir }},
+	{key: "attachments.max_size", env: "ATTACHMENT_MAX_SIZE", flag: "attachment-max-size", def: "10MiB", usage: "largest accepted attachment, such as 512KiB or 10MiB",
+		field: func(c *Config) any { return &c.Attachments.MaxSize }, positive: true},
+	{key: "attachments.allowed_types", env: "ATTACHMENT_ALLOWED_TYPES", flag: "attachment-allowed-types", usage: "comma separated media types accepted as attachments, such as image/*,application/pdf; empty for any",
+		field: func(c *Config) any { return &c.Attachments.AllowedTypes }},
+	{key: "recurrence.horizon", env: "RECURRENCE_HORIZON", flag: "recurrence-horizon", def: "168h", usage: "how far ahead occurrences of recurring tasks are created, 0 for once they are due",
+		field: func(c *Config) any { return &c.Recurrence.Horizon }},
+	{key: "recurrence.interval", env: "RECURRENCE_INTERVAL", flag: "recurrence-interval", def: "15m", usage: "how often recurring tasks are checked for occurrences to create",
+		field: func(c *Config) any { return &c.Recurrence.Interval }, positive: true},
+	{key: "reminders.interval", env: "REMINDER_INTERVAL", flag: "reminder-interval", def: "1m", usage: "how often due reminders are looked for and sent",
+		field: func(c *Config) any { return &c.Reminders.Interval }, positive: true},
+	{key: "reminders.max_delay", env: "REMINDER_MAX_DELAY", flag: "reminder-max-delay", def: "24h", usage: "how late a reminder may still be sent",
+		field: func(c *Config) any { return &c.Reminders.MaxDelay }, positive: true},
+	{key: "reminders.max_attempts", env: "REMINDER_MAX_ATTEMPTS", flag: "reminder-max-attempts", def: "8", usage: "how often sending a reminder is tried before giving up",
+		field: func(c *Config) any { return &c.Reminders.MaxAttempts }, positive: true},
+	{key: "reminders.channels", env: "REMINDER_CHANNELS", flag: "reminder-channels", def: LogChannel, usage: "comma separated channels reminders are sent through: log, webhook, email",
+		field: func(c *Config) any { return &c.Reminders.Channels }},
+	{key: "reminders.webhook_url", env: "REMINDER_WEBHOOK_URL", flag: "reminder-webhook-url", usage: "URL the webhook channel posts reminders to",
+		field: func(c *Config) any { return &c.Reminders.WebhookURL }},
+	{key: "reminders.webhook_secret", env: "REMINDER_WEBHOOK_SECRET", usage: "secret signing the webhook requests",
+		field: func(c *Config) any { return &c.Reminders.WebhookSecret }},
+	{key: "reminders.smtp_addr", env: "SMTP_ADDR", flag: "smtp-addr", usage: "host:port of the SMTP server of the email channel",
+		field: func(c *Config) any { return &c.Reminders.SMTPAddr }},
+	{key: "reminders.smtp_username", env: "SMTP_USERNAME", flag: "smtp-username", usage: "SMTP user, empty to send without authentication",
+		field: func(c *Config) any { return &c.Reminders.SMTPUsername }},
+	{key: "reminders.smtp_password", env: "SMTP_PASSWORD", usage: "SMTP password",
+		field: func(c *Config) any { return &c.Reminders.SMTPPassword }},
+	{key: "reminders.smtp_from", env: "SMTP_FROM", flag: "smtp-from", usage: "sender address of reminder emails",
+		field: func(c *Config) any { return &c.Reminders.SMTPFrom }},
+}
+
+// Load parses args (without the program name) and the environment into a
+// Config. The optional file is named by the -config flag or CONFIG_FILE and
+// read as TOML if it ends in .toml and as YAML otherwise. Every invalid
+// value is reported in the returned error, not just the first one.
+func Load(args []string) (Config, error) {
+	fs := flag.NewFlagSet("task_manager", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file")
+	flagValues := map[string]*flagValue{}
+	for _, s := range settings {
+		if s.flag != "" {
+			_, isBool := s.field(&Config{}).(*bool)
+			flagValues[s.key] = &flagValue{isBool: isBool}
+			fs.Var(flagValues[s.key], s.flag, s.usage+" (env "+s.env+")")
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	values := map[string]string{}
+	for _, s := range settings {
+		values[s.key] = s.def
+	}
+
+	if *configFile != "" {
+		fileValues, err := readFile(*configFile)
+		if err != nil {
+			return Config{}, err
+		}
+		for key, value := range fileValues {
+			values[key] = value
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok && value != "" {
+			values[s.key] = value
+		}
+	}
+
+	// only flags given on the command line override the other sources
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name {
+				values[s.key] = flagValues[s.key].value
+			}
+		}
+	})
+
+	var cfg Config
+	var errs []error
+	for _, s := range settings {
+		if err := s.set(&cfg, values[s.key]); err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %w", s.key, s.env, err))
+		}
+	}
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return Config{}, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return cfg, nil
+}
+
+// flagValue keeps a flag's raw value until Load knows which source wins.
+// Boolean settings are bool flags, so a bare -seed means -seed=true.
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (f *flagValue) String() string { return f.value }
+
+func (f *flagValue) Set(value string) error {
+	f.value = value
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool { return f.isBool }
+
+func (s setting) set(cfg *Config, value string) error {
+	switch field := s.field(cfg).(type) {
+	case *string:
+		*field = value
+	case *bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		*field = parsed
+	case *time.Duration:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 12h", value)
+		}
+		if parsed < 0 || (s.positive && parsed == 0) {
+			return fmt.Errorf("%q must be positive", value)
+		}
+		*field = parsed
+	case *int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		if parsed < 0 || (s.positive && parsed == 0) {
+			return fmt.Errorf("%q must be positive", value)
+		}
+		*field = parsed
+	case *int64:
+		parsed, err := parseSize(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a size such as 512KiB or 10MiB", value)
+		}
+		if parsed < 0 || (s.positive && parsed == 0) {
+			return fmt.Errorf("%q must be positive", value)
+		}
+		*field = parsed
+	case *[]string:
+		*field = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*field = append(*field, item)
+			}
+		}
+	case *slog.Level:
+		if err := field.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("%q is not one of debug, info, warn or error", value)
+		}
+	}
+	return nil
+}
+
+// sizeUnits are the suffixes parseSize accepts, longest first so "KiB" is
+// not taken for "B".
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3}, {"B", 1},
+}
+
+// parseSize reads a byte count with an optional unit, such as 2048, 512KiB
+// or 10MB.
+func parseSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			value, multiplier = strings.TrimSpace(number), unit.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt64/multiplier {
+		return 0, errors.New("size is too large")
+	}
+	return n * multiplier, nil
+}
+
+// validate checks rules that span several settings.
+func (cfg Config) validate() []error {
+	var errs []error
+	if cfg.Addr == "" {
+		errs = append(errs, errors.New("addr: can not be empty"))
+	}
+	switch cfg.Storage.Backend {
+	case MongoBackend:
+		if cfg.Storage.Mongo.URI == "" || cfg.Storage.Mongo.Database == "" || cfg.Storage.Mongo.TasksCollection == "" {
+			errs = append(errs, errors.New("mongo: uri, database and tasks_collection are required by the mongo backend"))
+		}
+	case MemoryBackend:
+	default:
+		errs = append(errs, fmt.Errorf("storage.backend: unknown backend %q", cfg.Storage.Backend))
+	}
+	if cfg.Auth.JWTSecret == "" {
+		errs = append(errs, errors.New("auth.jwt_secret (JWT_SECRET): is required"))
+	}
+	switch cfg.Attachments.Backend {
+	case FilesystemBlobs:
+		if cfg.Attachments.Dir == "" {
+			errs = append(errs, errors.New("attachments.dir: is required by the filesystem backend"))
+		}
+	case GridFSBlobs:
+		if cfg.Storage.Backend != MongoBackend {
+			errs = append(errs, errors.New("attachments.backend: gridfs needs the mongo storage backend"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("attachments.backend: unknown backend %q", cfg.Attachments.Backend))
+	}
+	for _, allowed := range cfg.Attachments.AllowedTypes {
+		if _, _, err := mime.ParseMediaType(allowed); err != nil || !strings.Contains(allowed, "/") {
+			errs = append(errs, fmt.Errorf("attachments.allowed_types: %q is not a media type such as image/png or image/*", allowed))
+		}
+	}
+	errs = append(errs, cfg.Reminders.validate()...)
+	if (cfg.Auth.AdminUsername == "") != (cfg.Auth.AdminPassword == "") {
+		errs = append(errs, errors.New("auth: admin_username and admin_password must be set together"))
+	}
+	return errs
+}
+
+// validate checks that every enabled reminder channel is configured.
+func (r Reminders) validate() []error {
+	var errs []error
+	for _, channel := range r.Channels {
+		switch channel {
+		case LogChannel:
+		case WebhookChannel:
+			if u, err := url.Parse(r.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, errors.New("reminders.webhook_url: the webhook channel needs an http or https URL"))
+			}
+			if r.WebhookSecret == "" {
+				errs = append(errs, errors.New("reminders.webhook_secret (REMINDER_WEBHOOK_SECRET): is required by the webhook channel"))
+			}
+		case EmailChannel:
+			if _, _, err := net.SplitHostPort(r.SMTPAddr); err != nil {
+				errs = append(errs, errors.New("reminders.smtp_addr: the email channel needs a host:port"))
+			}
+			if _, err := mail.ParseAddress(r.SMTPFrom); err != nil {
+				errs = append(errs, errors.New("reminders.smtp_from: the email channel needs a sender address"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("reminders.channels: unknown channel %q", channel))
+		}
+	}
+	return errs
+}
+
+// readFile reads a config file into dotted keys such as "mongo.uri",
+// rejecting keys that are not settings so typos do not go unnoticed.
+func readFile(path string) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	tree := map[string]any{}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(raw, &tree)
+	} else {
+		err = yaml.Unmarshal(raw, &tree)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", tree, values)
+
+	var unknown []string
+	for key := range values {
+		if !slices.ContainsFunc(settings, func(s setting) bool { return s.key == key }) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return nil, fmt.Errorf("unknown settings in config file %s: %s", path, strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
+
+func flatten(prefix string, tree map[string]any, values map[string]string) {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok {
+			flatten(key, nested, values)
+			continue
+		}
+		// lists, such as attachments.allowed_types, become comma separated
+		if list, ok := value.([]any); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+			continue
+		}
+		values[key] = fmt.Sprint(value)
+	}
+}
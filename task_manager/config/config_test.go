@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// loadTest runs Load with a clean environment apart from env, and a config
+// file holding file if it is not empty.
+func loadTest(t *testing.T, file string, env map[string]string, args ...string) (Config, error) {
+	t.Helper()
+	for _, s := range settings {
+		t.Setenv(s.env, "")
+	}
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("STORAGE_BACKEND", MemoryBackend)
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	if file != "" {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		args = append([]string{"-config", path}, args...)
+	}
+	return Load(args)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		addr string
+		ttl  time.Duration
+	}{
+		{name: "default", addr: "localhost:3000", ttl: 24 * time.Hour},
+		{name: "file over default", file: "addr: file:1\nauth:\n  jwt_ttl: 1h\n",
+			addr: "file:1", ttl: time.Hour},
+		{name: "env over file", file: "addr: file:1\nauth:\n  jwt_ttl: 1h\n", env: map[string]string{"ADDR": "env:2"},
+			addr: "env:2", ttl: time.Hour},
+		{name: "flag over env", file: "addr: file:1\nauth:\n  jwt_ttl: 1h\n", env: map[string]string{"ADDR": "env:2", "JWT_TTL": "2h"},
+			args: []string{"-addr", "flag:3"}, addr: "flag:3", ttl: 2 * time.Hour},
+		{name: "empty env is unset", file: "addr: file:1\n", env: map[string]string{"ADDR": ""},
+			addr: "file:1", ttl: 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadTest(t, tt.file, tt.env, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Addr != tt.addr || cfg.Auth.JWTTTL != tt.ttl {
+				t.Errorf("got addr %q and jwt_ttl %s, want %q and %s", cfg.Addr, cfg.Auth.JWTTTL, tt.addr, tt.ttl)
+			}
+		})
+	}
+}
+
+func TestLoadBoolFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		seed bool
+	}{
+		{name: "default", seed: false},
+		{name: "bare flag", args: []string{"-seed"}, seed: true},
+		{name: "bare flag before another flag", args: []string{"-seed", "-addr", "flag:3"}, seed: true},
+		{name: "env", env: map[string]string{"SEED_DATA": "true"}, seed: true},
+		{name: "flag turns env off", env: map[string]string{"SEED_DATA": "true"}, args: []string{"-seed=false"}, seed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadTest(t, "", tt.env, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Storage.Seed != tt.seed {
+				t.Errorf("got seed %v, want %v", cfg.Storage.Seed, tt.seed)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsInvalidBool(t *testing.T) {
+	if _, err := loadTest(t, "", nil, "-seed=maybe"); err == nil {
+		t.Fatal("got no error for -seed=maybe")
+	}
+}
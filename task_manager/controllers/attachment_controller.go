@@ -0,0 +1,305 @@
+package task_controllers
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"task_manager/config"
+	"task_manager/customError"
+	"task_manager/data"
+	"task_manager/middleware"
+	"task_manager/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is how much larger than the file an upload body may
+// be, leaving room for the multipart headers and boundaries.
+const multipartOverhead = 1 << 20
+
+// sniffLength is how many bytes http.DetectContentType looks at.
+const sniffLength = 512
+
+var errAttachmentTooLarge = errors.New("attachment is too large")
+
+// AttachmentController serves the files attached to tasks. The metadata of
+// an attachment is stored with its task, so adding or removing one is a
+// write to the task that honours If-Match and shows up in its history,
+// while the bytes are kept in the blob store.
+type AttachmentController struct {
+	tasks  data.AuditedTaskRepository
+	blobs  data.BlobStore
+	limits config.Attachments
+}
+
+func NewAttachmentController(tasks data.AuditedTaskRepository, blobs data.BlobStore, limits config.Attachments) *AttachmentController {
+	return &AttachmentController{tasks: tasks, blobs: blobs, limits: limits}
+}
+
+func (ac *AttachmentController) GetAttachments(c *gin.Context){
+	task, err := ac.tasks.GetTask(c.Request.Context(), c.Param("id"), currentActor(c))
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	attachments := task.Attachments
+	if attachments == nil{
+		attachments = []models.Attachment{}
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"attachments": attachments})
+}
+
+// UploadAttachment adds the file sent as the "file" field of a
+// multipart/form-data body to a task and responds with the task. The
+// content type is the one the part declares, or else sniffed from the
+// bytes, and must be one of the configured types. The file is streamed
+// to the blob store and hashed on the way, never held in memory whole.
+func (ac *AttachmentController) UploadAttachment(c *gin.Context){
+	version, err := ifMatchVersion(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	// fail before reading the body if the task can not be seen
+	if _, err := ac.tasks.GetTask(c.Request.Context(), c.Param("id"), currentActor(c)); err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, ac.limits.MaxSize+multipartOverhead)
+	reader, err := c.Request.MultipartReader()
+	if err != nil{
+		middleware.AbortWithProblem(c, middleware.Problem{
+			Status: http.StatusUnsupportedMediaType,
+			Detail: "Upload the file as multipart/form-data in a field named file",
+		})
+		return
+	}
+
+	var part *multipart.Part
+	for part == nil{
+		next, err := reader.NextPart()
+		if err == io.EOF{
+			errorHandler(c, &customError.BadRequestError{Reason: "The upload has no field named file"})
+			return
+		}
+		if err != nil{
+			ac.uploadError(c, err)
+			return
+		}
+		if next.FormName() == "file"{
+			part = next
+		}
+	}
+
+	body := bufio.NewReaderSize(part, sniffLength)
+	head, err := body.Peek(sniffLength)
+	if err != nil && err != io.EOF{
+		ac.uploadError(c, err)
+		return
+	}
+	contentType := attachmentContentType(part.Header.Get("Content-Type"), head)
+	if !typeAllowed(ac.limits.AllowedTypes, contentType){
+		middleware.AbortWithProblem(c, middleware.Problem{
+			Status: http.StatusUnsupportedMediaType,
+			Detail: fmt.Sprintf("Attachments of type %s are not accepted", contentType),
+		})
+		return
+	}
+
+	hash := sha256.New()
+	key := data.NewBlobKey()
+	upload := &sizeLimitReader{r: body, remaining: ac.limits.MaxSize}
+	size, err := ac.blobs.Put(c.Request.Context(), key, io.TeeReader(upload, hash))
+	if upload.err != nil{
+		// the client's fault rather than the blob store's
+		ac.uploadError(c, upload.err)
+		return
+	}
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	task, err := ac.tasks.AddAttachment(c.Request.Context(), c.Param("id"), currentActor(c), version, models.Attachment{
+		Name:        part.FileName(),
+		Size:        size,
+		ContentType: contentType,
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+		UploadedBy:  currentActor(c).UserID,
+		UploadedAt:  time.Now().UTC().Truncate(time.Millisecond),
+		BlobKey:     key,
+	})
+	if err != nil{
+		ac.deleteBlob(c.Request.Context(), key)
+		errorHandler(c, err)
+		return
+	}
+
+	attachment := task.Attachments[len(task.Attachments)-1]
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+strconv.Itoa(attachment.ID))
+	writeTask(c, http.StatusCreated, task)
+}
+
+// DownloadAttachment sends the bytes of an attachment. Range, If-Range and
+// the conditional headers are supported, with the checksum as the ETag.
+func (ac *AttachmentController) DownloadAttachment(c *gin.Context){
+	_, attachment, err := ac.attachmentParams(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	blob, err := ac.blobs.Open(c.Request.Context(), attachment.BlobKey)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+	defer blob.Close()
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name})
+	if disposition == ""{
+		disposition = "attachment"
+	}
+	c.Header("Content-Type", attachment.ContentType)
+	c.Header("Content-Disposition", disposition)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("ETag", strconv.Quote(attachment.Checksum))
+	http.ServeContent(c.Writer, c.Request, attachment.Name, attachment.UploadedAt, blob)
+}
+
+// DeleteAttachment takes an attachment off a task and deletes its bytes.
+// It honours If-Match and responds with the task.
+func (ac *AttachmentController) DeleteAttachment(c *gin.Context){
+	version, err := ifMatchVersion(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	_, attachment, err := ac.attachmentParams(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	task, err := ac.tasks.RemoveAttachment(c.Request.Context(), c.Param("id"), currentActor(c), version, attachment.ID)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+	ac.deleteBlob(c.Request.Context(), attachment.BlobKey)
+
+	writeTask(c, http.StatusOK, task)
+}
+
+// attachmentParams loads the task of the request, so attachments are only
+// reachable by those who can see it, and finds the attachment on it.
+func (ac *AttachmentController) attachmentParams(c *gin.Context) (models.Task, models.Attachment, error){
+	task, err := ac.tasks.GetTask(c.Request.Context(), c.Param("id"), currentActor(c))
+	if err != nil{
+		return models.Task{}, models.Attachment{}, err
+	}
+	attachmentID, err := strconv.Atoi(c.Param("attachment_id"))
+	if err != nil{
+		return models.Task{}, models.Attachment{}, &customError.BadRequestError{Reason: "Invalid format of attachment ID!"}
+	}
+	i := slices.IndexFunc(task.Attachments, func(a models.Attachment) bool{ return a.ID == attachmentID })
+	if i < 0{
+		return models.Task{}, models.Attachment{}, &customError.NotFoundError{Resource: "Attachment", ID: attachmentID}
+	}
+	return task, task.Attachments[i], nil
+}
+
+// uploadError answers an upload body that could not be read, telling
+// bodies over the size limit apart from malformed ones.
+func (ac *AttachmentController) uploadError(c *gin.Context, err error){
+	if errors.Is(err, errAttachmentTooLarge) || errors.As(err, new(*http.MaxBytesError)){
+		middleware.AbortWithProblem(c, middleware.Problem{
+			Status: http.StatusRequestEntityTooLarge,
+			Detail: fmt.Sprintf("Attachments can not be larger than %d bytes", ac.limits.MaxSize),
+		})
+		return
+	}
+	errorHandler(c, &customError.BadRequestError{Reason: "Could not read the upload: " + err.Error()})
+}
+
+// deleteBlob removes a blob no task refers to any more. A failure only
+// leaves an orphaned blob behind, so it is logged rather than reported.
+func (ac *AttachmentController) deleteBlob(ctx context.Context, key string){
+	if err := ac.blobs.Delete(context.WithoutCancel(ctx), key); err != nil{
+		log.Printf("Error deleting blob %s: %v", key, err)
+	}
+}
+
+// attachmentContentType returns the declared content type of an upload,
+// or the one sniffed from its first bytes if none or only the generic
+// application/octet-stream was declared.
+func attachmentContentType(declared string, head []byte) string{
+	if mediaType, params, err := mime.ParseMediaType(declared); err == nil && mediaType != "application/octet-stream"{
+		return mime.FormatMediaType(mediaType, params)
+	}
+	return http.DetectContentType(head)
+}
+
+// typeAllowed reports whether contentType matches one of the allowed media
+// types, which may end in "/*" to allow a whole family. No allowed types
+// means any type is accepted.
+func typeAllowed(allowed []string, contentType string) bool{
+	if len(allowed) == 0{
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil{
+		return false
+	}
+	for _, pattern := range allowed{
+		pattern = strings.ToLower(pattern)
+		if pattern == "*/*" || pattern == mediaType{
+			return true
+		}
+		if family, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mediaType, family+"/"){
+			return true
+		}
+	}
+	return false
+}
+
+// sizeLimitReader fails with errAttachmentTooLarge once more than
+// remaining bytes have been read, so the blob store discards the upload.
+// err keeps the failure, other than io.EOF, of reading the upload.
+type sizeLimitReader struct {
+	r         io.Reader
+	remaining int64
+	err       error
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error){
+	if int64(len(p)) > l.remaining+1{
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0{
+		err = errAttachmentTooLarge
+	}
+	if err != nil && err != io.EOF{
+		l.err = err
+		return 0, err
+	}
+	return n, err
+}
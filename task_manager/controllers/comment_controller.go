@@ -0,0 +1,183 @@
+package task_controllers
+
+import (
+	"net/http"
+	"strconv"
+	"task_manager/customError"
+	"task_manager/data"
+	"task_manager/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CommentController serves the comments of a task. Comments can be read
+// and written by everyone who can see the task, but only edited by their
+// author and only deleted by their author, the task's owner or an admin.
+type CommentController struct {
+	tasks    data.AuditedTaskRepository
+	comments data.CommentRepository
+}
+
+func NewCommentController(tasks data.AuditedTaskRepository, comments data.CommentRepository) *CommentController {
+	return &CommentController{tasks: tasks, comments: comments}
+}
+
+// commentBody is the body of PostComment and UpdateComment.
+type commentBody struct {
+	Body string `json:"body"`
+}
+
+func (cc *CommentController) GetComments(c *gin.Context){
+	task, err := cc.tasks.GetTask(c.Request.Context(), c.Param("id"), currentActor(c))
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	offset, limit, err := parsePaging(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	page, err := cc.comments.GetComments(c.Request.Context(), task.ID, offset, limit)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	if int64(page.Offset+len(page.Comments)) < page.Total{
+		page.Next = nextPageLink(c, page.Offset+page.Limit, page.Limit)
+	}
+	c.IndentedJSON(http.StatusOK, page)
+}
+
+// GetComment returns a single comment with its earlier bodies.
+func (cc *CommentController) GetComment(c *gin.Context){
+	task, commentID, err := cc.commentParams(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	comment, err := cc.comments.GetComment(c.Request.Context(), task.ID, commentID)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	writeComment(c, http.StatusOK, comment)
+}
+
+func (cc *CommentController) PostComment(c *gin.Context){
+	task, err := cc.tasks.GetTask(c.Request.Context(), c.Param("id"), currentActor(c))
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	var body commentBody
+	if err := c.ShouldBindJSON(&body); err != nil{
+		errorHandler(c, invalidJSONError(err))
+		return
+	}
+
+	comment, err := cc.comments.AddComment(c.Request.Context(), models.Comment{
+		TaskID:   task.ID,
+		AuthorID: currentActor(c).UserID,
+		Body:     body.Body,
+	})
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	writeComment(c, http.StatusCreated, comment)
+}
+
+// UpdateComment replaces the body of a comment, keeping the old one in its
+// edits. It honours If-Match.
+func (cc *CommentController) UpdateComment(c *gin.Context){
+	task, commentID, err := cc.commentParams(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	var body commentBody
+	if err := c.ShouldBindJSON(&body); err != nil{
+		errorHandler(c, invalidJSONError(err))
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	comment, err := cc.comments.GetComment(c.Request.Context(), task.ID, commentID)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+	if comment.AuthorID != currentActor(c).UserID{
+		errorHandler(c, &customError.ForbiddenError{Reason: "Only the author can edit a comment"})
+		return
+	}
+
+	comment, err = cc.comments.UpdateComment(c.Request.Context(), task.ID, commentID, version, body.Body)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	writeComment(c, http.StatusOK, comment)
+}
+
+// DeleteComment removes a comment for good. It honours If-Match.
+func (cc *CommentController) DeleteComment(c *gin.Context){
+	task, commentID, err := cc.commentParams(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	comment, err := cc.comments.GetComment(c.Request.Context(), task.ID, commentID)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+	actor := currentActor(c)
+	if comment.AuthorID != actor.UserID && task.OwnerID != actor.UserID && !actor.Admin{
+		errorHandler(c, &customError.ForbiddenError{Reason: "Only the author, the task's owner or an admin can delete a comment"})
+		return
+	}
+
+	if err := cc.comments.DeleteComment(c.Request.Context(), task.ID, commentID, version); err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// commentParams loads the task of the request, so comments are only
+// reachable by those who can see it, and parses the comment ID.
+func (cc *CommentController) commentParams(c *gin.Context) (models.Task, int, error){
+	task, err := cc.tasks.GetTask(c.Request.Context(), c.Param("id"), currentActor(c))
+	if err != nil{
+		return models.Task{}, 0, err
+	}
+	commentID, err := strconv.Atoi(c.Param("comment_id"))
+	if err != nil{
+		return models.Task{}, 0, &customError.BadRequestError{Reason: "Invalid format of comment ID!"}
+	}
+	return task, commentID, nil
+}
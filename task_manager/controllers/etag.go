@@ -0,0 +1,75 @@
+package task_controllers
+
+import (
+	"strconv"
+	"strings"
+	"task_manager/customError"
+	"task_manager/data"
+	"task_manager/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag renders a task's version as a strong entity tag.
+func etag(task models.Task) string{
+	return versionETag(task.Version)
+}
+
+func versionETag(version int) string{
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// setETag adds the ETag header for task to the response.
+func setETag(c *gin.Context, task models.Task){
+	c.Header("ETag", etag(task))
+}
+
+// ifMatchVersion turns the If-Match header into the version a write must
+// match. A missing header or "*" allows any version. Weak or malformed
+// tags can never match a strong ETag, so they fail the precondition.
+func ifMatchVersion(c *gin.Context) (int, error){
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*"{
+		return data.AnyVersion, nil
+	}
+
+	unquoted, err := strconv.Unquote(header)
+	if err == nil{
+		if version, err := strconv.Atoi(unquoted); err == nil && version > 0{
+			return version, nil
+		}
+	}
+	return 0, &customError.PreconditionFailedError{Reason: "If-Match must be a single ETag previously returned by the API"}
+}
+
+// notModified reports whether any tag in If-None-Match matches task.
+func notModified(c *gin.Context, task models.Task) bool{
+	header := c.GetHeader("If-None-Match")
+	if header == ""{
+		return false
+	}
+
+	current := etag(task)
+	for _, tag := range strings.Split(header, ","){
+		tag = strings.TrimSpace(tag)
+		// If-None-Match uses the weak comparison
+		tag = strings.TrimPrefix(tag, "W/")
+		if tag == "*" || tag == current{
+			return true
+		}
+	}
+	return false
+}
+
+// writeTask responds with task and its ETag.
+func writeTask(c *gin.Context, code int, task models.Task){
+	setETag(c, task)
+	c.IndentedJSON(code, task)
+}
+
+
+// writeComment responds with comment and its ETag.
+func writeComment(c *gin.Context, code int, comment models.Comment){
+	c.Header("ETag", versionETag(comment.Version))
+	c.IndentedJSON(code, comment)
+}
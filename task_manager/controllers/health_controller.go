@@ -0,0 +1,41 @@
+package task_controllers
+
+import (
+	"context"
+	"net/http"
+	"task_manager/data"
+	"task_manager/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct {
+	store    *data.Store
+	shutdown context.Context
+}
+
+// NewHealthController reports not ready once shutdown is cancelled, so load
+// balancers stop sending requests while the in-flight ones drain.
+func NewHealthController(shutdown context.Context, store *data.Store) *HealthController {
+	return &HealthController{store: store, shutdown: shutdown}
+}
+
+// Live reports that the process is up. It does not touch the database, so
+// a database outage does not get the process restarted.
+func (hc *HealthController) Live(c *gin.Context){
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Ready reports whether requests can be served, i.e. the server is not
+// shutting down and the database answers.
+func (hc *HealthController) Ready(c *gin.Context){
+	if hc.shutdown.Err() != nil{
+		middleware.AbortWithProblem(c, middleware.Problem{Status: http.StatusServiceUnavailable, Detail: "The server is shutting down"})
+		return
+	}
+	if err := hc.store.Ping(c.Request.Context()); err != nil{
+		errorHandler(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
@@ -0,0 +1,34 @@
+package task_controllers
+
+import (
+	"net/http"
+	"task_manager/data"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReminderController shows which reminders of a task went out, through
+// which channel and with what outcome, to everyone who can see the task.
+type ReminderController struct {
+	tasks     data.AuditedTaskRepository
+	reminders data.ReminderRepository
+}
+
+func NewReminderController(tasks data.AuditedTaskRepository, reminders data.ReminderRepository) *ReminderController {
+	return &ReminderController{tasks: tasks, reminders: reminders}
+}
+
+func (rc *ReminderController) GetReminders(c *gin.Context){
+	task, err := rc.tasks.GetTask(c.Request.Context(), c.Param("id"), currentActor(c))
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	deliveries, err := rc.reminders.GetTaskDeliveries(c.Request.Context(), task.ID)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"reminders": deliveries})
+}
@@ -1,57 +1,408 @@
 package task_controllers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 	"task_manager/customError"
 	"task_manager/data"
+	"task_manager/middleware"
 	"task_manager/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-func GetTasks(c *gin.Context){
-	tasks, err := data.GetAllTasks()
+type TaskController struct {
+	repo data.AuditedTaskRepository
+}
+
+func NewTaskController(repo data.AuditedTaskRepository) *TaskController {
+	return &TaskController{repo: repo}
+}
+
+func (tc *TaskController) GetTasks(c *gin.Context){
+	query, err := parseTaskQuery(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+	query.OwnerID = currentActor(c).OwnerScope()
+
+	page, err := tc.repo.GetAllTasks(c.Request.Context(), query)
 	if err != nil{
 		errorHandler(c, err)
 		return 
 	}
-	c.IndentedJSON(http.StatusOK, tasks)
+
+	if int64(page.Offset+len(page.Tasks)) < page.Total{
+		page.Next = nextPageLink(c, page.Offset+page.Limit, page.Limit)
+	}
+	c.IndentedJSON(http.StatusOK, page)
 }
 
-func GetATask(c *gin.Context){
+// GetTrash lists the caller's soft-deleted tasks, accepting the same query
+// parameters as GetTasks.
+func (tc *TaskController) GetTrash(c *gin.Context){
+	query, err := parseTaskQuery(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+	query.OwnerID = currentActor(c).OwnerScope()
+	query.Trash = true
+
+	page, err := tc.repo.GetAllTasks(c.Request.Context(), query)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	if int64(page.Offset+len(page.Tasks)) < page.Total{
+		page.Next = nextPageLink(c, page.Offset+page.Limit, page.Limit)
+	}
+	c.IndentedJSON(http.StatusOK, page)
+}
+
+// SearchTasks finds tasks by the words in their title and description,
+// given as q, best match first. It accepts the filters and paging of
+// GetTasks.
+func (tc *TaskController) SearchTasks(c *gin.Context){
+	query, err := parseTaskQuery(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+	query.OwnerID = currentActor(c).OwnerScope()
+
+	page, err := tc.repo.SearchTasks(c.Request.Context(), c.Query("q"), query)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	if int64(page.Offset+len(page.Hits)) < page.Total{
+		page.Next = nextPageLink(c, page.Offset+page.Limit, page.Limit)
+	}
+	c.IndentedJSON(http.StatusOK, page)
+}
+
+// RestoreDeletedTask takes a task back out of the trash.
+func (tc *TaskController) RestoreDeletedTask(c *gin.Context){
 	id := c.Param("id")
-	task, err := data.GetTask(id)
+
+	version, err := ifMatchVersion(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	task, err := tc.repo.RestoreDeletedTask(c.Request.Context(), id, currentActor(c), version)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	writeTask(c, http.StatusOK, task)
+}
+
+// GetOverdueTasks lists tasks past their due date that are not completed,
+// accepting the same query parameters as GetTasks.
+func (tc *TaskController) GetOverdueTasks(c *gin.Context){
+	q := c.Request.URL.Query()
+	q.Set("overdue", "true")
+	c.Request.URL.RawQuery = q.Encode()
+	tc.GetTasks(c)
+}
+
+func (tc *TaskController) GetATask(c *gin.Context){
+	id := c.Param("id")
+	task, err := tc.repo.GetTask(c.Request.Context(), id, currentActor(c))
 	
 	if err != nil{
 		errorHandler(c, err)
 		return
 	}
+
+	if notModified(c, task){
+		setETag(c, task)
+		c.Status(http.StatusNotModified)
+		return
+	}
 	
-	c.IndentedJSON(http.StatusOK, task)
+	writeTask(c, http.StatusOK, task)
 }
 
-func UpdateATask(c *gin.Context){
+func (tc *TaskController) UpdateATask(c *gin.Context){
 	id := c.Param("id")
 
 	var updatedTask models.Task
 	if err := c.ShouldBindJSON(&updatedTask) ; err != nil{
-		errorHandler(c, &customError.BadRequestError{Reason: "Invalid JSON"})
+		errorHandler(c, invalidJSONError(err))
 		return 
 	}
 
-	updatedTask, err := data.UpdateTask(id, updatedTask)
+	version, err := ifMatchVersion(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	updatedTask, err = tc.repo.UpdateTask(c.Request.Context(), id, currentActor(c), version, updatedTask)
 	if err != nil {
 		errorHandler(c, err)
 		return 
 	}
 
-	c.IndentedJSON(http.StatusOK, updatedTask)
+	writeTask(c, http.StatusOK, updatedTask)
+}
+
+func (tc *TaskController) PatchATask(c *gin.Context){
+	id := c.Param("id")
+
+	var format data.PatchFormat
+	switch c.ContentType(){
+	case "application/merge-patch+json", "application/json":
+		format = data.MergePatch
+	case "application/json-patch+json":
+		format = data.JSONPatch
+	default:
+		middleware.AbortWithProblem(c, middleware.Problem{
+			Status: http.StatusUnsupportedMediaType,
+			Detail: "Use application/merge-patch+json or application/json-patch+json",
+		})
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil{
+		errorHandler(c, &customError.BadRequestError{Reason: "Could not read request body"})
+		return
+	}
+
+	task, err := tc.repo.PatchTask(c.Request.Context(), id, currentActor(c), version, format, patch)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	writeTask(c, http.StatusOK, task)
+}
+
+// AddTags adds the tags listed in the body, {"tags": [...]}, to a task.
+func (tc *TaskController) AddTags(c *gin.Context){
+	var body struct {
+		Tags []string `json:"tags" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil{
+		errorHandler(c, invalidJSONError(err))
+		return
+	}
+	tc.updateTags(c, body.Tags, nil)
+}
+
+// RemoveTag takes a single tag off a task.
+func (tc *TaskController) RemoveTag(c *gin.Context){
+	tc.updateTags(c, nil, []string{c.Param("tag")})
+}
+
+func (tc *TaskController) updateTags(c *gin.Context, add, remove []string){
+	version, err := ifMatchVersion(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	task, err := tc.repo.UpdateTags(c.Request.Context(), c.Param("id"), currentActor(c), version, add, remove)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	writeTask(c, http.StatusOK, task)
+}
+
+// AddAssignees assigns the users listed in the body, {"user_ids": [...]},
+// to a task.
+func (tc *TaskController) AddAssignees(c *gin.Context){
+	tc.addTaskUsers(c, models.Assignees)
+}
+
+// RemoveAssignee unassigns a single user from a task.
+func (tc *TaskController) RemoveAssignee(c *gin.Context){
+	tc.removeTaskUser(c, models.Assignees)
+}
+
+// AddWatchers adds the users listed in the body, {"user_ids": [...]}, to
+// the watchers of a task.
+func (tc *TaskController) AddWatchers(c *gin.Context){
+	tc.addTaskUsers(c, models.Watchers)
+}
+
+// RemoveWatcher takes a single user off the watchers of a task.
+func (tc *TaskController) RemoveWatcher(c *gin.Context){
+	tc.removeTaskUser(c, models.Watchers)
+}
+
+func (tc *TaskController) addTaskUsers(c *gin.Context, list models.UserList){
+	var body struct {
+		UserIDs []int `json:"user_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil{
+		errorHandler(c, invalidJSONError(err))
+		return
+	}
+	tc.updateTaskUsers(c, list, body.UserIDs, nil)
+}
+
+func (tc *TaskController) removeTaskUser(c *gin.Context, list models.UserList){
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil{
+		errorHandler(c, &customError.BadRequestError{Reason: "Invalid format of user ID!"})
+		return
+	}
+	tc.updateTaskUsers(c, list, nil, []int{userID})
+}
+
+func (tc *TaskController) updateTaskUsers(c *gin.Context, list models.UserList, add, remove []int){
+	version, err := ifMatchVersion(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	task, err := tc.repo.UpdateTaskUsers(c.Request.Context(), c.Param("id"), currentActor(c), version, list, add, remove)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	writeTask(c, http.StatusOK, task)
+}
+
+// GetTags lists the tags of the caller's tasks with how many tasks carry
+// each. It accepts the filters of GetTasks.
+func (tc *TaskController) GetTags(c *gin.Context){
+	query, err := parseTaskQuery(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+	query.OwnerID = currentActor(c).OwnerScope()
+
+	tags, err := tc.repo.TagCounts(c.Request.Context(), query)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// TransitionTask moves a task to another status, enforcing the workflow.
+// Illegal transitions are answered with 409 Conflict.
+func (tc *TaskController) TransitionTask(c *gin.Context){
+	id := c.Param("id")
+
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Status == ""{
+		errorHandler(c, &customError.BadRequestError{Reason: "Body must be {\"status\": \"<target status>\"}"})
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	patch, err := json.Marshal(body)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	task, err := tc.repo.PatchTask(c.Request.Context(), id, currentActor(c), version, data.MergePatch, patch)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	writeTask(c, http.StatusOK, task)
 }
 
-func DeleteATask(c *gin.Context){
+// GetTaskGraph returns the subtasks, parents and blockers linked to a
+// task, directly or through other tasks.
+func (tc *TaskController) GetTaskGraph(c *gin.Context){
+	graph, err := tc.repo.GetTaskGraph(c.Request.Context(), c.Param("id"), currentActor(c))
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, graph)
+}
+
+func (tc *TaskController) GetTaskHistory(c *gin.Context){
 	id := c.Param("id")
 
-	err := data.DeleteTask(id)
+	history, err := tc.repo.GetTaskHistory(c.Request.Context(), id, currentActor(c))
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, history)
+}
+
+// RestoreTask copies the values of an earlier version back onto the task.
+// The restore is itself a new version and honours If-Match.
+func (tc *TaskController) RestoreTask(c *gin.Context){
+	id := c.Param("id")
+
+	fromVersion, err := strconv.Atoi(c.Param("version"))
+	if err != nil{
+		errorHandler(c, &customError.BadRequestError{Reason: "Invalid format of version!"})
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	task, err := tc.repo.RestoreTask(c.Request.Context(), id, currentActor(c), version, fromVersion)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	writeTask(c, http.StatusOK, task)
+}
+
+func (tc *TaskController) DeleteATask(c *gin.Context){
+	id := c.Param("id")
+
+	version, err := ifMatchVersion(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	err = tc.repo.DeleteTask(c.Request.Context(), id, currentActor(c), version)
 	if err != nil{
 		errorHandler(c, err)
 		return 
@@ -60,29 +411,420 @@ func DeleteATask(c *gin.Context){
 	c.Status(http.StatusNoContent)
 }
 
-func PostTask(c *gin.Context){
+// bulkItemResult reports the outcome of one operation of a bulk request.
+type bulkItemResult struct {
+	Index  int                 `json:"index"`
+	Op     models.BulkOp       `json:"op"`
+	ID     int                 `json:"id,omitempty"`
+	Status int                 `json:"status"`
+	Task   *models.Task        `json:"task,omitempty"`
+	Error  *middleware.Problem `json:"error,omitempty"`
+}
+
+var bulkSuccessStatus = map[models.BulkOp]int{
+	models.BulkCreate: http.StatusCreated,
+	models.BulkUpdate: http.StatusOK,
+	models.BulkDelete: http.StatusNoContent,
+}
+
+// BulkTasks runs a batch of operations and answers 200 with one result per
+// operation, each carrying the status the single-task endpoint would have
+// answered with.
+func (tc *TaskController) BulkTasks(c *gin.Context){
+	var ops []models.BulkOperation
+	if err := c.ShouldBindJSON(&ops); err != nil{
+		errorHandler(c, invalidJSONError(err))
+		return
+	}
+
+	actor := currentActor(c)
+	for i := range ops{
+		if ops[i].Op == models.BulkCreate && ops[i].Task != nil{
+			ops[i].Task.OwnerID = actor.UserID
+		}
+	}
+
+	results, err := tc.repo.BulkWrite(c.Request.Context(), actor, ops)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	items := make([]bulkItemResult, len(results))
+	for i, result := range results{
+		items[i] = bulkItemResult{Index: i, Op: ops[i].Op, ID: ops[i].ID, Status: bulkSuccessStatus[ops[i].Op], Task: result.Task}
+		if result.Task != nil{
+			items[i].ID = result.Task.ID
+		}
+		if result.Err != nil{
+			problem := problemFor(c, result.Err).WithDefaults(c)
+			items[i].Status, items[i].Error = problem.Status, &problem
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"results": items})
+}
+
+// ExportTasks streams the caller's tasks as csv, json or ndjson, picked by
+// the format parameter. It accepts the filters and ordering of GetTasks but
+// no paging: every matching task is written.
+func (tc *TaskController) ExportTasks(c *gin.Context){
+	name := c.DefaultQuery("format", "json")
+	format, ok := transferFormats[name]
+	if !ok{
+		errorHandler(c, &customError.BadRequestError{Reason: "Format must be one of csv, json or ndjson"})
+		return
+	}
+
+	query, err := parseTaskQuery(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+	query.OwnerID = currentActor(c).OwnerScope()
+	query.Offset, query.Limit = 0, 0
+
+	// the headers go out with the first task, so an export that can not
+	// start is still answered with a problem
+	var encoder taskEncoder
+	start := func(){
+		c.Header("Content-Type", format.contentType)
+		c.Header("Content-Disposition", `attachment; filename="tasks.`+name+`"`)
+		c.Status(http.StatusOK)
+		encoder = format.newEncoder(c.Writer)
+	}
+
+	err = tc.repo.EachTask(c.Request.Context(), query, func(task models.Task) error{
+		if encoder == nil{
+			start()
+		}
+		return encoder.Encode(task)
+	})
+	if err == nil{
+		if encoder == nil{
+			start()
+		}
+		err = encoder.Close()
+	}
+
+	switch {
+	case err == nil:
+	case encoder == nil:
+		errorHandler(c, err)
+	default:
+		log.Printf("Export on %s aborted: %v", c.Request.URL.Path, err)
+		abortStream(c)
+	}
+}
+
+// abortStream drops the connection of a response that failed after its
+// status was sent, so the client sees a cut off transfer rather than a
+// complete one.
+func abortStream(c *gin.Context){
+	c.Abort()
+	if conn, _, err := c.Writer.Hijack(); err == nil{
+		conn.Close()
+	}
+}
+
+// importRowError reports a row that was not imported; rows count from 1
+// and the CSV header row is not counted.
+type importRowError struct {
+	Row    int                `json:"row"`
+	Status int                `json:"status"`
+	Error  middleware.Problem `json:"error"`
+}
+
+type importReport struct {
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []importRowError `json:"errors"`
+}
+
+func (report *importReport) fail(c *gin.Context, row int, err error){
+	problem := problemFor(c, err).WithDefaults(c)
+	report.Failed++
+	report.Errors = append(report.Errors, importRowError{Row: row, Status: problem.Status, Error: problem})
+}
+
+// ImportTasks creates a task for every row of a csv, json or ndjson body,
+// picked by the format parameter or else the Content-Type, with the same
+// rules as PostTask. The body is read and written in batches, so it is
+// never held in memory whole, and the rows that failed are reported.
+func (tc *TaskController) ImportTasks(c *gin.Context){
+	name := c.Query("format")
+	if name == ""{
+		name = transferFormatNames[c.ContentType()]
+	}
+	format, ok := transferFormats[name]
+	if !ok{
+		middleware.AbortWithProblem(c, middleware.Problem{
+			Status: http.StatusUnsupportedMediaType,
+			Detail: "Use text/csv, application/json or application/x-ndjson, or pass format=csv|json|ndjson",
+		})
+		return
+	}
+
+	decoder, err := format.newDecoder(c.Request.Body)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	actor := currentActor(c)
+	report := importReport{Errors: []importRowError{}}
+	ops := make([]models.BulkOperation, 0, data.MaxBulkOperations)
+	rows := make([]int, 0, data.MaxBulkOperations)
+	flush := func() error{
+		if len(ops) == 0{
+			return nil
+		}
+		results, err := tc.repo.BulkWrite(c.Request.Context(), actor, ops)
+		if err != nil{
+			return err
+		}
+		for i, result := range results{
+			if result.Err != nil{
+				report.fail(c, rows[i], result.Err)
+			} else {
+				report.Imported++
+			}
+		}
+		ops, rows = ops[:0], rows[:0]
+		return nil
+	}
+
+	for row := 1; ; row++{
+		task, err := decoder.Decode()
+		if err == io.EOF{
+			break
+		}
+		var unreadable *unreadableError
+		if errors.As(err, &unreadable){
+			report.fail(c, row, &customError.BadRequestError{Reason: unreadable.Error()})
+			break
+		}
+		if err != nil{
+			report.fail(c, row, err)
+			continue
+		}
+
+		// imported tasks get new IDs, so links to the old ones are dropped
+		task.OwnerID, task.ParentID, task.BlockedBy = actor.UserID, 0, nil
+		ops = append(ops, models.BulkOperation{Op: models.BulkCreate, Task: &task})
+		rows = append(rows, row)
+		if len(ops) == data.MaxBulkOperations{
+			if err := flush(); err != nil{
+				errorHandler(c, err)
+				return
+			}
+		}
+	}
+
+	if err := flush(); err != nil{
+		errorHandler(c, err)
+		return
+	}
+	// rows that could not be parsed are reported before the batch they
+	// were read with is written
+	slices.SortFunc(report.Errors, func(a, b importRowError) int{ return a.Row - b.Row })
+	c.JSON(http.StatusOK, report)
+}
+
+func (tc *TaskController) PostTask(c *gin.Context){
 	var newTask models.Task
 	if err := c.ShouldBindJSON(&newTask); err != nil{
-		errorHandler(c, &customError.BadRequestError{Reason: "Invalid JSON"})
+		errorHandler(c, invalidJSONError(err))
 		return
 	}
 	
-	task, err := data.AddATask(newTask)
+	newTask.OwnerID = middleware.CurrentClaims(c).UserID
+	task, err := tc.repo.AddATask(c.Request.Context(), newTask)
 	if err != nil {
 		errorHandler(c, err)
 		return
 	}
 	
-	c.IndentedJSON(http.StatusCreated, task)
+	writeTask(c, http.StatusCreated, task)
+}
+
+// invalidJSONError wraps a body decoding failure, keeping the decoder's
+// explanation so clients can tell which field was wrong.
+func invalidJSONError(err error) error{
+	return &customError.BadRequestError{Reason: "Invalid JSON: " + err.Error()}
+}
+
+// currentActor describes the authenticated user for the repository, which
+// limits regular users to their own tasks while admins see everyone's.
+func currentActor(c *gin.Context) models.Actor{
+	claims := middleware.CurrentClaims(c)
+	return models.Actor{UserID: claims.UserID, Admin: claims.IsAdmin()}
 }
 
 func errorHandler(c *gin.Context, err error){
-	switch err.(type){
-		case *customError.NotFoundError:
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error":err.Error()})
-		case *customError.BadRequestError:
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		default:
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+	middleware.AbortWithProblem(c, problemFor(c, err))
+}
+
+// problemFor describes err as a problem details body. The cause of server
+// side errors is logged rather than sent to the client.
+func problemFor(c *gin.Context, err error) middleware.Problem{
+	problem := middleware.Problem{Status: errorStatus(err), Detail: err.Error()}
+
+	var invalid *customError.ValidationError
+	if errors.As(err, &invalid){
+		problem.Detail = "Some fields are invalid"
+		problem.Errors = invalid.Fields
+	}
+
+	switch problem.Status{
+	case http.StatusInternalServerError:
+		problem.Detail = "Unexpected error"
+	case http.StatusServiceUnavailable:
+		problem.Detail = "The database is unavailable, please retry"
+	case http.StatusGatewayTimeout:
+		problem.Detail = "The database did not respond in time"
+	}
+	if problem.Status >= http.StatusInternalServerError{
+		// the cause may name hosts and driver internals, so it is only logged
+		log.Printf("Error on %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	}
+	return problem
+}
+
+// errorStatus picks the HTTP status for err. errors.As also finds errors
+// the data layer wrapped with extra context.
+func errorStatus(err error) int{
+	switch {
+	case errors.As(err, new(*customError.ValidationError)):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, new(*customError.BadRequestError)):
+		return http.StatusBadRequest
+	case errors.As(err, new(*customError.NotFoundError)):
+		return http.StatusNotFound
+	case errors.As(err, new(*customError.ConflictError)):
+		return http.StatusConflict
+	case errors.As(err, new(*customError.UnauthorizedError)):
+		return http.StatusUnauthorized
+	case errors.As(err, new(*customError.ForbiddenError)):
+		return http.StatusForbidden
+	case errors.As(err, new(*customError.PreconditionFailedError)):
+		return http.StatusPreconditionFailed
+	case errors.As(err, new(*customError.UnavailableError)), errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	case data.IsTimeout(err):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseTaskQuery reads the listing filters from the query string. Value
+// checks beyond "is it a number" are left to the repository.
+func parseTaskQuery(c *gin.Context) (models.TaskQuery, error){
+	query := models.TaskQuery{
+		Status:  models.Status(c.Query("status")),
+		Title:   c.Query("title"),
+		SortBy:  c.Query("sort"),
+		Overdue: c.Query("overdue") == "true",
+		AllTags: queryTags(c, "tags"),
+		AnyTags: queryTags(c, "any_tags"),
+		Recurring: c.Query("recurring") == "true",
+		HasReminders: c.Query("has_reminders") == "true",
+	}
+
+	if dueAfter := c.Query("due_after"); dueAfter != ""{
+		date, _, err := models.ParseDueDate(dueAfter)
+		if err != nil{
+			return query, &customError.BadRequestError{Reason: err.Error()}
+		}
+		query.DueAfter = date
+	}
+	if dueBefore := c.Query("due_before"); dueBefore != ""{
+		date, dateOnly, err := models.ParseDueDate(dueBefore)
+		if err != nil{
+			return query, &customError.BadRequestError{Reason: err.Error()}
+		}
+		// a bare date covers the whole day
+		if dateOnly{
+			date = date.AddDate(0, 0, 1).Add(-time.Millisecond)
+		}
+		query.DueBefore = date
+	}
+
+	switch c.DefaultQuery("order", "asc"){
+	case "asc":
+	case "desc":
+		query.SortDesc = true
+	default:
+		return query, &customError.BadRequestError{Reason: "Order must be either 'asc' or 'desc'"}
+	}
+
+	var err error
+	if parentID := c.Query("parent_id"); parentID != ""{
+		if query.ParentID, err = strconv.Atoi(parentID); err != nil || query.ParentID <= 0{
+			return query, &customError.BadRequestError{Reason: "parent_id must be a task ID"}
+		}
+	}
+	if assigneeID := c.Query("assignee_id"); assigneeID != ""{
+		if query.AssigneeID, err = strconv.Atoi(assigneeID); err != nil || query.AssigneeID <= 0{
+			return query, &customError.BadRequestError{Reason: "assignee_id must be a user ID"}
 		}
+	}
+	if watcherID := c.Query("watcher_id"); watcherID != ""{
+		if query.WatcherID, err = strconv.Atoi(watcherID); err != nil || query.WatcherID <= 0{
+			return query, &customError.BadRequestError{Reason: "watcher_id must be a user ID"}
+		}
+	}
+	if offset := c.Query("offset"); offset != ""{
+		if query.Offset, err = strconv.Atoi(offset); err != nil{
+			return query, &customError.BadRequestError{Reason: "Offset must be a number"}
+		}
+	}
+	if limit := c.Query("limit"); limit != ""{
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit == 0{
+			return query, &customError.BadRequestError{Reason: "Limit must be between 1 and 100"}
+		}
+	}
+
+	return query, nil
+}
+
+// parsePaging reads offset and limit for the listings that take no other
+// parameters; the repository checks their range.
+func parsePaging(c *gin.Context) (offset, limit int, err error){
+	if value := c.Query("offset"); value != ""{
+		if offset, err = strconv.Atoi(value); err != nil{
+			return 0, 0, &customError.BadRequestError{Reason: "Offset must be a number"}
+		}
+	}
+	if value := c.Query("limit"); value != ""{
+		if limit, err = strconv.Atoi(value); err != nil || limit == 0{
+			return 0, 0, &customError.BadRequestError{Reason: "Limit must be between 1 and 100"}
+		}
+	}
+	return offset, limit, nil
+}
+
+// queryTags reads a comma separated list of tags, which may also be
+// repeated: ?tags=a,b and ?tags=a&tags=b are the same.
+func queryTags(c *gin.Context, param string) []string{
+	var tags []string
+	for _, value := range c.QueryArray(param){
+		for _, tag := range strings.Split(value, ","){
+			if tag = models.NormalizeTag(tag); tag != ""{
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return models.NormalizeTags(tags)
+}
+
+// nextPageLink rebuilds the current request URL with the offset and limit
+// of the following page, keeping every other query parameter intact.
+func nextPageLink(c *gin.Context, offset, limit int) string{
+	next := *c.Request.URL
+	params := next.Query()
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("limit", strconv.Itoa(limit))
+	next.RawQuery = params.Encode()
+	return next.RequestURI()
 }
\ No newline at end of file
@@ -0,0 +1,345 @@
+package task_controllers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"task_manager/customError"
+	"task_manager/models"
+	"time"
+)
+
+// transferFormat reads and writes task lists for import and export.
+type transferFormat struct {
+	contentType string
+	newEncoder  func(w io.Writer) taskEncoder
+	newDecoder  func(r io.Reader) (taskDecoder, error)
+}
+
+var transferFormats = map[string]transferFormat{
+	"json":   {"application/json", newJSONEncoder, newJSONDecoder},
+	"ndjson": {"application/x-ndjson", newNDJSONEncoder, newNDJSONDecoder},
+	"csv":    {"text/csv", newCSVEncoder, newCSVDecoder},
+}
+
+// transferFormatNames maps the content types accepted by import to formats.
+var transferFormatNames = map[string]string{
+	"application/json":     "json",
+	"application/x-ndjson": "ndjson",
+	"application/ndjson":   "ndjson",
+	"text/csv":             "csv",
+}
+
+// taskEncoder writes tasks one at a time; Close completes the document.
+type taskEncoder interface {
+	Encode(task models.Task) error
+	Close() error
+}
+
+// taskDecoder reads tasks one at a time and returns io.EOF after the last.
+// An error about a single row leaves the decoder usable, while an
+// *unreadableError means the rest of the input can not be parsed.
+type taskDecoder interface {
+	Decode() (models.Task, error)
+}
+
+type unreadableError struct {
+	err error
+}
+
+func (e *unreadableError) Error() string {
+	return "The rest of the input could not be read: " + e.err.Error()
+}
+
+func (e *unreadableError) Unwrap() error {
+	return e.err
+}
+
+// jsonEncoder writes a JSON array, one task per line.
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONEncoder(w io.Writer) taskEncoder {
+	return &jsonEncoder{w: w}
+}
+
+func (e *jsonEncoder) Encode(task models.Task) error {
+	raw, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if e.count == 0 {
+		separator = "[\n"
+	}
+	e.count++
+	_, err = io.WriteString(e.w, separator+string(raw))
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+type ndjsonEncoder struct {
+	*json.Encoder
+}
+
+func newNDJSONEncoder(w io.Writer) taskEncoder {
+	return ndjsonEncoder{json.NewEncoder(w)}
+}
+
+func (e ndjsonEncoder) Encode(task models.Task) error {
+	return e.Encoder.Encode(task)
+}
+
+func (e ndjsonEncoder) Close() error {
+	return nil
+}
+
+// csvColumns are the columns written by export. Import reads the same
+// columns but ignores those assigned by the server.
+var csvColumns = []string{"id", "title", "description", "due_date", "status", "tags", "assignees", "watchers", "reminders", "recurrence", "owner_id", "version", "overdue"}
+
+// csvListSeparator separates the values in the tags, assignees, watchers
+// and reminders columns.
+const csvListSeparator = ","
+
+type csvEncoder struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func newCSVEncoder(w io.Writer) taskEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) Encode(task models.Task) error {
+	if !e.headerWritten {
+		e.headerWritten = true
+		if err := e.w.Write(csvColumns); err != nil {
+			return err
+		}
+	}
+
+	dueDate := ""
+	if !task.DueDate.IsZero() {
+		dueDate = task.DueDate.Format(time.RFC3339Nano)
+	}
+	return e.w.Write([]string{
+		strconv.Itoa(task.ID),
+		task.Title,
+		task.Description,
+		dueDate,
+		string(task.Status),
+		strings.Join(task.Tags, csvListSeparator),
+		joinIDs(task.Assignees),
+		joinIDs(task.Watchers),
+		joinIDs(task.Reminders),
+		task.Recurrence,
+		strconv.Itoa(task.OwnerID),
+		strconv.Itoa(task.Version),
+		strconv.FormatBool(task.IsOverdue(time.Now())),
+	})
+}
+
+func (e *csvEncoder) Close() error {
+	if !e.headerWritten {
+		e.headerWritten = true
+		if err := e.w.Write(csvColumns); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonDecoder reads the elements of a JSON array one by one, so the whole
+// array is never held in memory.
+type jsonDecoder struct {
+	d *json.Decoder
+}
+
+func newJSONDecoder(r io.Reader) (taskDecoder, error) {
+	d := json.NewDecoder(r)
+	if token, err := d.Token(); err != nil || token != json.Delim('[') {
+		return nil, &customError.BadRequestError{Reason: "A JSON import must be an array of tasks"}
+	}
+	return &jsonDecoder{d: d}, nil
+}
+
+func (d *jsonDecoder) Decode() (models.Task, error) {
+	if !d.d.More() {
+		if _, err := d.d.Token(); err != nil {
+			return models.Task{}, &unreadableError{err}
+		}
+		return models.Task{}, io.EOF
+	}
+	return decodeJSONTask(d.d)
+}
+
+// decodeJSONTask tells a task the decoder could not use apart from input
+// it can not continue after.
+func decodeJSONTask(d *json.Decoder) (models.Task, error) {
+	var task models.Task
+	err := d.Decode(&task)
+	if errors.As(err, new(*json.SyntaxError)) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return models.Task{}, &unreadableError{err}
+	}
+	if err != nil {
+		return models.Task{}, invalidJSONError(err)
+	}
+	return task, nil
+}
+
+// ndjsonDecoder reads one task per line. Lines are independent, so a
+// malformed line only fails its own row.
+type ndjsonDecoder struct {
+	s *bufio.Scanner
+}
+
+func newNDJSONDecoder(r io.Reader) (taskDecoder, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	return &ndjsonDecoder{s: s}, nil
+}
+
+func (d *ndjsonDecoder) Decode() (models.Task, error) {
+	for d.s.Scan() {
+		line := bytes.TrimSpace(d.s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var task models.Task
+		if err := json.Unmarshal(line, &task); err != nil {
+			return models.Task{}, invalidJSONError(err)
+		}
+		return task, nil
+	}
+	if err := d.s.Err(); err != nil {
+		return models.Task{}, &unreadableError{err}
+	}
+	return models.Task{}, io.EOF
+}
+
+// csvDecoder reads rows by the column names of the header row, so columns
+// may come in any order and the server-assigned ones may be left out.
+type csvDecoder struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVDecoder(r io.Reader) (taskDecoder, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, &customError.BadRequestError{Reason: "A CSV import must start with a header row"}
+	}
+	reader.ReuseRecord = true
+
+	columns := map[string]int{}
+	var unknown []string
+	for i, name := range header {
+		// spreadsheets often save UTF-8 with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(csvColumns, name) {
+			unknown = append(unknown, name)
+		}
+		columns[name] = i
+	}
+	if len(unknown) > 0 {
+		return nil, &customError.BadRequestError{Reason: fmt.Sprintf("Unknown CSV columns: %s", strings.Join(unknown, ", "))}
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, &customError.BadRequestError{Reason: "A CSV import needs a title column"}
+	}
+	return &csvDecoder{r: reader, columns: columns}, nil
+}
+
+func (d *csvDecoder) Decode() (models.Task, error) {
+	record, err := d.r.Read()
+	if err == io.EOF {
+		return models.Task{}, io.EOF
+	}
+	if err != nil {
+		return models.Task{}, &customError.BadRequestError{Reason: "Invalid CSV: " + err.Error()}
+	}
+
+	field := func(name string) string {
+		if i, ok := d.columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	task := models.Task{
+		Title:       field("title"),
+		Description: field("description"),
+		Status:      models.Status(field("status")),
+		Recurrence:  field("recurrence"),
+	}
+	if tags := field("tags"); tags != "" {
+		task.Tags = models.NormalizeTags(strings.FieldsFunc(tags, func(r rune) bool { return strings.ContainsRune(csvListSeparator, r) }))
+	}
+	for _, list := range models.UserLists {
+		ids, err := splitIDs(field(string(list)))
+		if err != nil {
+			invalid := &customError.ValidationError{}
+			invalid.Add(string(list), "must be user IDs separated by commas")
+			return models.Task{}, invalid
+		}
+		*task.Users(list) = ids
+	}
+	if task.Reminders, err = splitIDs(field("reminders")); err != nil {
+		invalid := &customError.ValidationError{}
+		invalid.Add("reminders", "must be minutes separated by commas")
+		return models.Task{}, invalid
+	}
+	if dueDate := field("due_date"); dueDate != "" {
+		task.DueDate, _, err = models.ParseDueDate(dueDate)
+		if err != nil {
+			invalid := &customError.ValidationError{}
+			invalid.Add("due_date", "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+			return models.Task{}, invalid
+		}
+	}
+	return task, nil
+}
+
+func joinIDs(ids []int) string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.Itoa(id)
+	}
+	return strings.Join(values, csvListSeparator)
+}
+
+// splitIDs parses a column written by joinIDs.
+func splitIDs(value string) ([]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var ids []int
+	for _, part := range strings.Split(value, csvListSeparator) {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return models.NormalizeIDs(ids), nil
+}
@@ -0,0 +1,305 @@
+package task_controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"task_manager/auth"
+	"task_manager/customError"
+	"task_manager/data"
+	"task_manager/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserController struct {
+	users  data.UserRepository
+	tasks  data.AuditedTaskRepository
+	tokens *auth.TokenService
+}
+
+func NewUserController(users data.UserRepository, tasks data.AuditedTaskRepository, tokens *auth.TokenService) *UserController {
+	return &UserController{users: users, tasks: tasks, tokens: tokens}
+}
+
+func (uc *UserController) Register(c *gin.Context){
+	var credentials models.Credentials
+	if err := c.ShouldBindJSON(&credentials); err != nil{
+		errorHandler(c, &customError.BadRequestError{Reason: "Invalid JSON"})
+		return
+	}
+
+	hash, err := auth.HashPassword(credentials.Password)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	user, err := uc.users.AddUser(c.Request.Context(), models.User{
+		Username:     credentials.Username,
+		PasswordHash: hash,
+		Role:         models.RoleUser,
+	})
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, user)
+}
+
+func (uc *UserController) Login(c *gin.Context){
+	var credentials models.Credentials
+	if err := c.ShouldBindJSON(&credentials); err != nil{
+		errorHandler(c, &customError.BadRequestError{Reason: "Invalid JSON"})
+		return
+	}
+
+	invalid := &customError.UnauthorizedError{Reason: "Invalid username or password"}
+
+	user, err := uc.users.GetUserByUsername(c.Request.Context(), credentials.Username)
+	if err != nil{
+		if _, ok := err.(*customError.NotFoundError); ok{
+			err = invalid
+		}
+		errorHandler(c, err)
+		return
+	}
+
+	if !auth.CheckPassword(user.PasswordHash, credentials.Password){
+		errorHandler(c, invalid)
+		return
+	}
+
+	token, err := uc.tokens.Issue(user)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"token": token, "user": user})
+}
+
+// ListUsers pages through every user, so anyone can find the people to
+// assign a task to.
+func (uc *UserController) ListUsers(c *gin.Context){
+	offset, limit, err := parsePaging(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	page, err := uc.users.ListUsers(c.Request.Context(), offset, limit)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	if int64(page.Offset+len(page.Users)) < page.Total{
+		page.Next = nextPageLink(c, page.Offset+page.Limit, page.Limit)
+	}
+	c.IndentedJSON(http.StatusOK, page)
+}
+
+func (uc *UserController) GetUser(c *gin.Context){
+	id, err := userIDParam(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	user, err := uc.users.GetUser(c.Request.Context(), id)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, user)
+}
+
+// CreateUser lets admins add users of either role; everyone else signs up
+// through Register.
+func (uc *UserController) CreateUser(c *gin.Context){
+	if !currentActor(c).Admin{
+		errorHandler(c, &customError.ForbiddenError{Reason: "Only admins can create users"})
+		return
+	}
+
+	var input models.UserInput
+	if err := c.ShouldBindJSON(&input); err != nil{
+		errorHandler(c, invalidJSONError(err))
+		return
+	}
+
+	user := models.User{Role: models.RoleUser}
+	if input.Password == nil{
+		input.Password = new(string)
+	}
+	user, err := applyUserInput(user, input)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	user, err = uc.users.AddUser(c.Request.Context(), user)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, user)
+}
+
+// UpdateUser changes the fields given in the body. Users may change their
+// own username and password; only admins may edit other users or change
+// roles, and not their own, so the last admin can not lock themselves out.
+func (uc *UserController) UpdateUser(c *gin.Context){
+	id, err := userIDParam(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	actor := currentActor(c)
+	if !actor.Admin && id != actor.UserID{
+		errorHandler(c, &customError.ForbiddenError{Reason: "Only admins can change other users"})
+		return
+	}
+
+	var input models.UserInput
+	if err := c.ShouldBindJSON(&input); err != nil{
+		errorHandler(c, invalidJSONError(err))
+		return
+	}
+
+	user, err := uc.users.GetUser(c.Request.Context(), id)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+	if input.Role != nil && *input.Role != user.Role{
+		if !actor.Admin{
+			errorHandler(c, &customError.ForbiddenError{Reason: "Only admins can change roles"})
+			return
+		}
+		if id == actor.UserID{
+			errorHandler(c, &customError.ForbiddenError{Reason: "Admins can not change their own role"})
+			return
+		}
+	}
+
+	user, err = applyUserInput(user, input)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	user, err = uc.users.UpdateUser(c.Request.Context(), user)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, user)
+}
+
+// DeleteUser removes a user and takes them off every task they are
+// assigned to or watch. The tasks they own are kept.
+func (uc *UserController) DeleteUser(c *gin.Context){
+	id, err := userIDParam(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	actor := currentActor(c)
+	if !actor.Admin{
+		errorHandler(c, &customError.ForbiddenError{Reason: "Only admins can delete users"})
+		return
+	}
+	if id == actor.UserID{
+		errorHandler(c, &customError.ForbiddenError{Reason: "Admins can not delete their own account"})
+		return
+	}
+
+	if err := uc.users.DeleteUser(c.Request.Context(), id); err != nil{
+		errorHandler(c, err)
+		return
+	}
+	if _, err := uc.tasks.RemoveUser(c.Request.Context(), id); err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetUserTasks lists the tasks assigned to a user, accepting the filters
+// and paging of GetTasks. Users see every task assigned to themselves, but
+// only their own tasks among those assigned to others; admins see all.
+func (uc *UserController) GetUserTasks(c *gin.Context){
+	id, err := userIDParam(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	query, err := parseTaskQuery(c)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	if _, err := uc.users.GetUser(c.Request.Context(), id); err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	actor := currentActor(c)
+	query.AssigneeID = id
+	query.OwnerID = actor.OwnerScope()
+	if id == actor.UserID{
+		query.OwnerID = data.AllOwners
+	}
+
+	page, err := uc.tasks.GetAllTasks(c.Request.Context(), query)
+	if err != nil{
+		errorHandler(c, err)
+		return
+	}
+
+	if int64(page.Offset+len(page.Tasks)) < page.Total{
+		page.Next = nextPageLink(c, page.Offset+page.Limit, page.Limit)
+	}
+	c.IndentedJSON(http.StatusOK, page)
+}
+
+func userIDParam(c *gin.Context) (int, error){
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil{
+		return 0, &customError.BadRequestError{Reason: "Invalid format of ID!"}
+	}
+	return id, nil
+}
+
+// applyUserInput copies the fields set in input onto user, hashing a new
+// password. The result is validated by the repository.
+func applyUserInput(user models.User, input models.UserInput) (models.User, error){
+	if input.Username != nil{
+		user.Username = *input.Username
+	}
+	if input.Role != nil{
+		user.Role = *input.Role
+	}
+	if input.Email != nil{
+		user.Email = strings.TrimSpace(*input.Email)
+	}
+	if input.Password != nil{
+		hash, err := auth.HashPassword(*input.Password)
+		if err != nil{
+			return models.User{}, err
+		}
+		user.PasswordHash = hash
+	}
+	return user, nil
+}
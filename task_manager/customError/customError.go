@@ -1,6 +1,9 @@
 package customError
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type BadRequestError struct {
 	Reason string
@@ -11,9 +14,96 @@ func (err *BadRequestError) Error() string {
 }
 
 type NotFoundError struct {
+	Resource string // defaults to "Task"
 	ID int
 }
 
 func (err *NotFoundError) Error() string{
-	return fmt.Sprintf("Task with ID %d not found!", err.ID)
-}
\ No newline at end of file
+	resource := err.Resource
+	if resource == "" {
+		resource = "Task"
+	}
+	return fmt.Sprintf("%s with ID %d not found!", resource, err.ID)
+}
+
+type ConflictError struct {
+	Reason string
+}
+
+func (err *ConflictError) Error() string {
+	return fmt.Sprintf("Conflict: %s", err.Reason)
+}
+
+type UnauthorizedError struct {
+	Reason string
+}
+
+func (err *UnauthorizedError) Error() string {
+	return fmt.Sprintf("Unauthorized: %s", err.Reason)
+}
+
+type ForbiddenError struct {
+	Reason string
+}
+
+func (err *ForbiddenError) Error() string {
+	return fmt.Sprintf("Forbidden: %s", err.Reason)
+}
+
+type PreconditionFailedError struct {
+	Reason string
+}
+
+func (err *PreconditionFailedError) Error() string {
+	return fmt.Sprintf("Precondition failed: %s", err.Reason)
+}
+
+// UnavailableError means a dependency such as the database could not be
+// reached. Err keeps the underlying cause for logging.
+type UnavailableError struct {
+	Reason string
+	Err    error
+}
+
+func (err *UnavailableError) Error() string {
+	if err.Err != nil {
+		return fmt.Sprintf("Service unavailable: %s: %v", err.Reason, err.Err)
+	}
+	return fmt.Sprintf("Service unavailable: %s", err.Reason)
+}
+
+func (err *UnavailableError) Unwrap() error {
+	return err.Err
+}
+
+// FieldError explains why a single input field was rejected.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (err *ValidationError) Error() string {
+	reasons := make([]string, len(err.Fields))
+	for i, field := range err.Fields {
+		reasons[i] = field.Field + ": " + field.Reason
+	}
+	return fmt.Sprintf("Validation failed: %s", strings.Join(reasons, "; "))
+}
+
+// Add records a rejected field.
+func (err *ValidationError) Add(field, reason string) {
+	err.Fields = append(err.Fields, FieldError{Field: field, Reason: reason})
+}
+
+// OrNil returns err if any field was rejected and nil otherwise, so a
+// validator can collect every problem before returning.
+func (err *ValidationError) OrNil() error {
+	if len(err.Fields) == 0 {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package data
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"task_manager/customError"
+	"task_manager/models"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxAttachmentsPerTask   = 50
+	MaxAttachmentNameLength = 255
+)
+
+// attachmentCounterID is the _id of the counter handing out attachment IDs,
+// which are unique across tasks.
+const attachmentCounterID = "attachments"
+
+// validateAttachment checks the metadata of an attachment about to be
+// added. Size, checksum and content type are computed by the server, so
+// only the name, which comes from the client, can be wrong.
+func validateAttachment(attachment models.Attachment) error {
+	invalid := &customError.ValidationError{}
+	name := attachment.Name
+	if strings.TrimSpace(name) == "" {
+		invalid.Add("name", "can not be empty")
+	}
+	if utf8.RuneCountInString(name) > MaxAttachmentNameLength {
+		invalid.Add("name", fmt.Sprintf("can not be longer than %d characters", MaxAttachmentNameLength))
+	}
+	if strings.ContainsFunc(name, func(r rune) bool { return unicode.IsControl(r) || r == '/' || r == '\\' }) {
+		invalid.Add("name", "can not contain control characters or path separators")
+	}
+	return invalid.OrNil()
+}
+
+// addAttachment returns task with attachment added and its version bumped.
+func addAttachment(task models.Task, attachment models.Attachment) (models.Task, error) {
+	if err := validateAttachment(attachment); err != nil {
+		return models.Task{}, err
+	}
+	if len(task.Attachments) >= MaxAttachmentsPerTask {
+		invalid := &customError.ValidationError{}
+		invalid.Add("attachments", fmt.Sprintf("can not hold more than %d attachments", MaxAttachmentsPerTask))
+		return models.Task{}, invalid
+	}
+	task.Attachments = append(slices.Clone(task.Attachments), attachment)
+	task.Version++
+	return task, nil
+}
+
+// removeAttachment returns task without the attachment with attachmentID
+// and its version bumped.
+func removeAttachment(task models.Task, attachmentID int) (models.Task, error) {
+	i := slices.IndexFunc(task.Attachments, func(a models.Attachment) bool { return a.ID == attachmentID })
+	if i < 0 {
+		return models.Task{}, attachmentNotFoundError(attachmentID)
+	}
+	task.Attachments = slices.Delete(slices.Clone(task.Attachments), i, i+1)
+	if len(task.Attachments) == 0 {
+		task.Attachments = nil
+	}
+	task.Version++
+	return task, nil
+}
+
+func attachmentNotFoundError(attachmentID int) error {
+	return &customError.NotFoundError{Resource: "Attachment", ID: attachmentID}
+}
@@ -0,0 +1,391 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"sort"
+	"task_manager/customError"
+	"task_manager/models"
+	"time"
+)
+
+// AuditedTaskRepository is a TaskRepository whose writes are recorded in the
+// task history, which can be listed and used to restore older versions.
+type AuditedTaskRepository interface {
+	TaskRepository
+	GetTaskHistory(ctx context.Context, id string, actor models.Actor) ([]models.TaskHistoryEntry, error)
+	// RestoreTask copies the values the task had at fromVersion back onto it.
+	RestoreTask(ctx context.Context, id string, actor models.Actor, version, fromVersion int) (models.Task, error)
+}
+
+type auditedTaskRepository struct {
+	TaskRepository
+	history HistoryRepository
+}
+
+// NewAuditedTaskRepository wraps tasks so every create, update and delete is
+// written to history. It works with any backend: the previous state is read
+// first and the write is made conditional on that state's version, so the
+// recorded old values are exactly the ones that were replaced.
+func NewAuditedTaskRepository(tasks TaskRepository, history HistoryRepository) AuditedTaskRepository {
+	return &auditedTaskRepository{TaskRepository: tasks, history: history}
+}
+
+func (r *auditedTaskRepository) AddATask(ctx context.Context, task models.Task) (models.Task, error) {
+	created, err := r.TaskRepository.AddATask(ctx, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+	r.record(ctx, models.ActionCreate, created.OwnerID, nil, &created)
+	return created, nil
+}
+
+func (r *auditedTaskRepository) UpdateTask(ctx context.Context, id string, actor models.Actor, version int, updatedTask models.Task) (models.Task, error) {
+	return r.update(ctx, models.ActionUpdate, 0, id, actor, version, func(expected int) (models.Task, error) {
+		return r.TaskRepository.UpdateTask(ctx, id, actor, expected, updatedTask)
+	})
+}
+
+func (r *auditedTaskRepository) PatchTask(ctx context.Context, id string, actor models.Actor, version int, format PatchFormat, patch []byte) (models.Task, error) {
+	return r.update(ctx, models.ActionUpdate, 0, id, actor, version, func(expected int) (models.Task, error) {
+		return r.TaskRepository.PatchTask(ctx, id, actor, expected, format, patch)
+	})
+}
+
+func (r *auditedTaskRepository) UpdateTags(ctx context.Context, id string, actor models.Actor, version int, add, remove []string) (models.Task, error) {
+	return r.update(ctx, models.ActionUpdate, 0, id, actor, version, func(expected int) (models.Task, error) {
+		return r.TaskRepository.UpdateTags(ctx, id, actor, expected, add, remove)
+	})
+}
+
+func (r *auditedTaskRepository) UpdateTaskUsers(ctx context.Context, id string, actor models.Actor, version int, list models.UserList, add, remove []int) (models.Task, error) {
+	return r.update(ctx, models.ActionUpdate, 0, id, actor, version, func(expected int) (models.Task, error) {
+		return r.TaskRepository.UpdateTaskUsers(ctx, id, actor, expected, list, add, remove)
+	})
+}
+
+func (r *auditedTaskRepository) AddAttachment(ctx context.Context, id string, actor models.Actor, version int, attachment models.Attachment) (models.Task, error) {
+	return r.update(ctx, models.ActionUpdate, 0, id, actor, version, func(expected int) (models.Task, error) {
+		return r.TaskRepository.AddAttachment(ctx, id, actor, expected, attachment)
+	})
+}
+
+func (r *auditedTaskRepository) RemoveAttachment(ctx context.Context, id string, actor models.Actor, version int, attachmentID int) (models.Task, error) {
+	return r.update(ctx, models.ActionUpdate, 0, id, actor, version, func(expected int) (models.Task, error) {
+		return r.TaskRepository.RemoveAttachment(ctx, id, actor, expected, attachmentID)
+	})
+}
+
+// PutTask records fixture writes with actor 0, as a create if nothing was
+// stored under the ID before and as an update otherwise.
+func (r *auditedTaskRepository) PutTask(ctx context.Context, task models.Task) (PutResult, error) {
+	result, err := r.TaskRepository.PutTask(ctx, task)
+	if err != nil || !result.Written {
+		return result, err
+	}
+	if result.Previous == nil {
+		r.record(ctx, models.ActionCreate, 0, nil, &result.Task)
+	} else {
+		r.record(ctx, models.ActionUpdate, 0, result.Previous, &result.Task)
+	}
+	return result, nil
+}
+
+// AdvanceSeries records its writes with actor 0, as occurrences are
+// created by the system on behalf of the series rather than by a user.
+func (r *auditedTaskRepository) AdvanceSeries(ctx context.Context, head models.Task) (SeriesAdvance, error) {
+	advance, err := r.TaskRepository.AdvanceSeries(ctx, head)
+	if err != nil {
+		return SeriesAdvance{}, err
+	}
+	if advance.Created {
+		r.record(ctx, models.ActionCreate, 0, nil, advance.Next)
+	}
+	if advance.Head.Version != advance.Previous.Version {
+		r.record(ctx, models.ActionUpdate, 0, &advance.Previous, &advance.Head)
+	}
+	return advance, nil
+}
+
+func (r *auditedTaskRepository) DeleteTask(ctx context.Context, id string, actor models.Actor, version int) error {
+	oldTask, err := r.ownedTask(ctx, id, actor)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return err
+	}
+
+	err = r.TaskRepository.DeleteTask(ctx, id, actor, oldTask.Version)
+	if err != nil {
+		return unconditionalRace(err, oldTask.ID, version)
+	}
+
+	r.recordDelete(ctx, actor.UserID, oldTask)
+	return nil
+}
+
+func (r *auditedTaskRepository) RestoreDeletedTask(ctx context.Context, id string, actor models.Actor, version int) (models.Task, error) {
+	task, err := r.TaskRepository.RestoreDeletedTask(ctx, id, actor, version)
+	if err != nil {
+		return models.Task{}, err
+	}
+	r.record(ctx, models.ActionUndelete, actor.UserID, nil, &task)
+	return task, nil
+}
+
+func (r *auditedTaskRepository) BulkWrite(ctx context.Context, actor models.Actor, ops []models.BulkOperation) ([]BulkResult, error) {
+	results, err := r.TaskRepository.BulkWrite(ctx, actor, ops)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		switch ops[i].Op {
+		case models.BulkCreate:
+			r.record(ctx, models.ActionCreate, actor.UserID, nil, result.Task)
+		case models.BulkUpdate:
+			r.record(ctx, models.ActionUpdate, actor.UserID, result.Previous, result.Task)
+		case models.BulkDelete:
+			r.recordDelete(ctx, actor.UserID, *result.Previous)
+		}
+	}
+	return results, nil
+}
+
+// PurgeDeletedTasks records purges, and the tasks that lost their links to
+// the purged ones, with actor 0, as they are made by the system rather
+// than a user.
+func (r *auditedTaskRepository) PurgeDeletedTasks(ctx context.Context, deletedBefore time.Time) (PurgeResult, error) {
+	result, err := r.TaskRepository.PurgeDeletedTasks(ctx, deletedBefore)
+	for i := range result.Purged {
+		r.record(ctx, models.ActionPurge, 0, &result.Purged[i], nil)
+	}
+	r.recordChanges(ctx, 0, result.Unlinked)
+	return result, err
+}
+
+// RemoveUser records the changes with actor 0, as they are made by the
+// system when a user is deleted. Changes made before a failure are still
+// recorded.
+func (r *auditedTaskRepository) RemoveUser(ctx context.Context, userID int) ([]TaskChange, error) {
+	changes, err := r.TaskRepository.RemoveUser(ctx, userID)
+	r.recordChanges(ctx, 0, changes)
+	return changes, err
+}
+
+func (r *auditedTaskRepository) GetTaskHistory(ctx context.Context, id string, actor models.Actor) ([]models.TaskHistoryEntry, error) {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// only people who can see the task may read its history; admins can
+	// also read the history of deleted tasks
+	_, err = r.TaskRepository.GetTask(ctx, id, actor)
+	deleted := false
+	if err != nil {
+		if _, notFound := err.(*customError.NotFoundError); !notFound || !actor.Admin {
+			return nil, err
+		}
+		deleted = true
+	}
+
+	history, err := r.history.GetTaskHistory(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if deleted && len(history) == 0 {
+		return nil, &customError.NotFoundError{ID: taskID}
+	}
+	return history, nil
+}
+
+func (r *auditedTaskRepository) RestoreTask(ctx context.Context, id string, actor models.Actor, version, fromVersion int) (models.Task, error) {
+	history, err := r.GetTaskHistory(ctx, id, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	var snapshot *models.Task
+	for _, entry := range history {
+		if entry.New != nil && entry.New.Version == fromVersion {
+			snapshot = entry.New
+		}
+	}
+	if snapshot == nil {
+		return models.Task{}, &customError.NotFoundError{Resource: "Version", ID: fromVersion}
+	}
+
+	// the rule of a series lives on its latest occurrence, so an older
+	// occurrence gets it back only if the series has not moved on since
+	restored := *snapshot
+	if restored.Recurrence != "" {
+		moved, err := r.seriesMovedOn(ctx, restored)
+		if err != nil {
+			return models.Task{}, err
+		}
+		if moved {
+			restored.Recurrence = ""
+		}
+	}
+
+	return r.update(ctx, models.ActionRestore, fromVersion, id, actor, version, func(expected int) (models.Task, error) {
+		return r.TaskRepository.UpdateTask(ctx, id, actor, expected, restored)
+	})
+}
+
+// seriesMovedOn reports whether another task of the series of task carries
+// the series' rule now.
+func (r *auditedTaskRepository) seriesMovedOn(ctx context.Context, task models.Task) (bool, error) {
+	moved := false
+	err := r.TaskRepository.EachTask(ctx, models.TaskQuery{OwnerID: task.OwnerID, Recurring: true}, func(head models.Task) error {
+		if head.ID != task.ID && seriesOf(head) == seriesOf(task) {
+			moved = true
+		}
+		return nil
+	})
+	return moved, err
+}
+
+// update reads the current task, runs write conditioned on its version and
+// records the change. restoredFrom is only set for restores.
+func (r *auditedTaskRepository) update(ctx context.Context, action models.HistoryAction, restoredFrom int, id string, actor models.Actor, version int, write func(expected int) (models.Task, error)) (models.Task, error) {
+	oldTask, err := r.ownedTask(ctx, id, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return models.Task{}, err
+	}
+
+	newTask, err := write(oldTask.Version)
+	if err != nil {
+		return models.Task{}, unconditionalRace(err, oldTask.ID, version)
+	}
+
+	// patches that change nothing do not bump the version and leave no trace
+	if newTask.Version != oldTask.Version {
+		entry := newHistoryEntry(action, actor.UserID, &oldTask, &newTask)
+		entry.RestoredFrom = restoredFrom
+		r.addEntry(ctx, entry)
+	}
+	return newTask, nil
+}
+
+// ownedTask returns the task a write by actor is about. GetTask also finds
+// tasks the actor is assigned to or watches, which they may open but not
+// change.
+func (r *auditedTaskRepository) ownedTask(ctx context.Context, id string, actor models.Actor) (models.Task, error) {
+	task, err := r.TaskRepository.GetTask(ctx, id, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if !ownedBy(task, actor.OwnerScope()) {
+		return models.Task{}, &customError.NotFoundError{ID: task.ID}
+	}
+	return task, nil
+}
+
+// unconditionalRace turns the 412 caused by our own version condition back
+// into the 409 an unconditional write gets when it loses a race.
+func unconditionalRace(err error, taskID, version int) error {
+	if _, stale := err.(*customError.PreconditionFailedError); stale && version == AnyVersion {
+		return concurrentWriteError(taskID, version)
+	}
+	return err
+}
+
+// recordDelete records moving oldTask to the trash, which bumped its
+// version.
+func (r *auditedTaskRepository) recordDelete(ctx context.Context, actorID int, oldTask models.Task) {
+	entry := newHistoryEntry(models.ActionDelete, actorID, &oldTask, nil)
+	entry.Version = oldTask.Version + 1
+	r.addEntry(ctx, entry)
+}
+
+func (r *auditedTaskRepository) recordChanges(ctx context.Context, actorID int, changes []TaskChange) {
+	for i := range changes {
+		r.record(ctx, models.ActionUpdate, actorID, &changes[i].Previous, &changes[i].Task)
+	}
+}
+
+func (r *auditedTaskRepository) record(ctx context.Context, action models.HistoryAction, actorID int, oldTask, newTask *models.Task) {
+	r.addEntry(ctx, newHistoryEntry(action, actorID, oldTask, newTask))
+}
+
+// addEntry stores entry. The task write has already happened at this
+// point, so a failure is logged with the full entry instead of failing the
+// request, which would invite the client to repeat a write that succeeded.
+// For the same reason the entry is still written if the client has gone
+// away in the meantime.
+func (r *auditedTaskRepository) addEntry(ctx context.Context, entry models.TaskHistoryEntry) {
+	if err := r.history.AddEntry(context.WithoutCancel(ctx), entry); err != nil {
+		raw, _ := json.Marshal(entry)
+		log.Printf("AUDIT LOSS: %v; entry: %s", err, raw)
+	}
+}
+
+func newHistoryEntry(action models.HistoryAction, actorID int, oldTask, newTask *models.Task) models.TaskHistoryEntry {
+	entry := models.TaskHistoryEntry{
+		Action:    action,
+		ActorID:   actorID,
+		Timestamp: time.Now().UTC().Truncate(time.Millisecond),
+		Old:       oldTask,
+		New:       newTask,
+		Changes:   diffTasks(oldTask, newTask),
+	}
+	if newTask != nil {
+		entry.TaskID, entry.Version = newTask.ID, newTask.Version
+	} else {
+		entry.TaskID, entry.Version = oldTask.ID, oldTask.Version
+	}
+	return entry
+}
+
+// diffTasks lists the fields that differ between two task states, using
+// the API's field names. A nil side stands for "did not exist".
+func diffTasks(oldTask, newTask *models.Task) []models.FieldChange {
+	oldFields, newFields := taskFields(oldTask), taskFields(newTask)
+
+	names := map[string]bool{}
+	for name := range oldFields {
+		names[name] = true
+	}
+	for name := range newFields {
+		names[name] = true
+	}
+
+	changes := []models.FieldChange{}
+	for name := range names {
+		// bookkeeping and computed fields are not changes in their own right
+		if name == "version" || name == "overdue" {
+			continue
+		}
+		if !reflect.DeepEqual(oldFields[name], newFields[name]) {
+			changes = append(changes, models.FieldChange{Field: name, Old: oldFields[name], New: newFields[name]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func taskFields(task *models.Task) map[string]any {
+	fields := map[string]any{}
+	if task == nil {
+		return fields
+	}
+	raw, err := json.Marshal(task)
+	if err == nil {
+		err = json.Unmarshal(raw, &fields)
+	}
+	if err != nil {
+		log.Printf("Error converting task %d for diffing: %v", task.ID, err)
+	}
+	return fields
+}
@@ -0,0 +1,54 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"task_manager/config"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// BlobStore keeps the bytes of attachments, which are too large to be
+// stored with their task. Keys are chosen by the caller with NewBlobKey.
+//
+// Put and Open are not bounded by the database timeouts, as how long they
+// take depends on how fast the client sends or reads the bytes; the
+// caller's context still stops them.
+type BlobStore interface {
+	// Put stores everything read from r under key and returns the number
+	// of bytes stored. If r fails, nothing is kept.
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open returns the bytes stored under key for reading, from any
+	// offset so ranges can be served.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the blob stored under key. Deleting a blob that does
+	// not exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// NewBlobKey returns a random key for a new blob.
+func NewBlobKey() string {
+	key := make([]byte, 16)
+	// crypto/rand does not fail on supported platforms
+	_, _ = rand.Read(key)
+	return hex.EncodeToString(key)
+}
+
+// newBlobStore builds the attachment backend selected by cfg.Backend. db is
+// nil unless the mongo storage backend is in use.
+func newBlobStore(cfg config.Attachments, db *mongo.Database) (BlobStore, error) {
+	switch cfg.Backend {
+	case config.FilesystemBlobs:
+		return NewFilesystemBlobStore(cfg.Dir)
+	case config.GridFSBlobs:
+		if db == nil {
+			return nil, fmt.Errorf("the %s attachment backend needs the mongo storage backend", cfg.Backend)
+		}
+		return NewGridFSBlobStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown attachment backend %q", cfg.Backend)
+	}
+}
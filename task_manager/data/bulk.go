@@ -0,0 +1,100 @@
+package data
+
+import (
+	"fmt"
+	"task_manager/customError"
+	"task_manager/models"
+	"time"
+)
+
+// MaxBulkOperations bounds the size of a single bulk request.
+const MaxBulkOperations = 1000
+
+// BulkResult is the outcome of one bulk operation. Err is set if the
+// operation failed; otherwise Task holds the created or updated task (nil
+// for deletes) and Previous the state it replaced (nil for creates).
+type BulkResult struct {
+	Task     *models.Task
+	Previous *models.Task
+	Err      error
+}
+
+// checkBulkOperations runs the checks that need no stored data and returns
+// one result per operation, with Err set on those that can not run. A task
+// may only be named once per batch, as every operation is checked against
+// the state from before the batch.
+func checkBulkOperations(ops []models.BulkOperation) ([]BulkResult, error) {
+	if len(ops) == 0 {
+		return nil, &customError.BadRequestError{Reason: "A bulk request needs at least one operation"}
+	}
+	if len(ops) > MaxBulkOperations {
+		return nil, &customError.BadRequestError{Reason: fmt.Sprintf("A bulk request can hold at most %d operations", MaxBulkOperations)}
+	}
+
+	results := make([]BulkResult, len(ops))
+	seen := map[int]bool{}
+	for i, op := range ops {
+		switch op.Op {
+		case models.BulkCreate:
+			if op.Task == nil {
+				results[i].Err = &customError.BadRequestError{Reason: "create needs a task"}
+			}
+		case models.BulkUpdate, models.BulkDelete:
+			switch {
+			case op.ID <= 0:
+				results[i].Err = &customError.BadRequestError{Reason: fmt.Sprintf("%s needs the id of a task", op.Op)}
+			case op.Op == models.BulkUpdate && op.Task == nil:
+				results[i].Err = &customError.BadRequestError{Reason: "update needs a task"}
+			case seen[op.ID]:
+				results[i].Err = &customError.BadRequestError{Reason: fmt.Sprintf("Task with ID %d appears more than once in the batch", op.ID)}
+			}
+			seen[op.ID] = true
+		default:
+			results[i].Err = &customError.BadRequestError{Reason: "op must be one of create, update or delete"}
+		}
+	}
+	return results, nil
+}
+
+// bulkCreate checks a create and returns the task to insert, still without
+// an ID.
+func bulkCreate(op models.BulkOperation) (models.Task, error) {
+	if err := validateNewTask(*op.Task); err != nil {
+		return models.Task{}, err
+	}
+	task := *op.Task
+	task.Version = 1
+	task.Attachments = nil
+	task.SeriesID, task.Occurrence = 0, 0
+	task.DeletedAt = nil
+	return task, nil
+}
+
+// bulkUpdate checks an update against the stored task and returns the
+// replacement, with the same rules as UpdateTask.
+func bulkUpdate(oldTask models.Task, op models.BulkOperation) (models.Task, error) {
+	if err := checkVersion(oldTask, op.Version); err != nil {
+		return models.Task{}, err
+	}
+	if err := validateTaskUpdate(oldTask, *op.Task); err != nil {
+		return models.Task{}, err
+	}
+	task := *op.Task
+	task.ID = oldTask.ID
+	task.OwnerID = oldTask.OwnerID
+	task.Attachments = oldTask.Attachments
+	task.SeriesID, task.Occurrence = oldTask.SeriesID, oldTask.Occurrence
+	task.Version = oldTask.Version + 1
+	task.DeletedAt = nil
+	return task, nil
+}
+
+// bulkDelete checks a delete and returns the task as it will be trashed.
+func bulkDelete(oldTask models.Task, op models.BulkOperation, deletedAt time.Time) (models.Task, error) {
+	if err := checkVersion(oldTask, op.Version); err != nil {
+		return models.Task{}, err
+	}
+	oldTask.DeletedAt = &deletedAt
+	oldTask.Version++
+	return oldTask, nil
+}
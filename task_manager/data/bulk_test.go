@@ -0,0 +1,136 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"task_manager/customError"
+	"task_manager/models"
+	"testing"
+)
+
+func bulkUpdateOp(task models.Task, version int, title string) models.BulkOperation {
+	task.Title = title
+	return models.BulkOperation{Op: models.BulkUpdate, ID: task.ID, Version: version, Task: &task}
+}
+
+func isPreconditionFailed(err error) bool {
+	var stale *customError.PreconditionFailedError
+	return errors.As(err, &stale)
+}
+
+func TestBulkWriteRejectsStaleVersions(t *testing.T) {
+	ctx := context.Background()
+	tasks := NewMemoryTaskRepository(false, NewMemoryUserRepository())
+	stale := addLinkedTask(t, tasks, 0)
+	fresh := addLinkedTask(t, tasks, 0)
+	trashed := addLinkedTask(t, tasks, 0)
+
+	moved := stale
+	moved.Title = "Moved on"
+	if _, err := updateLinkedTask(tasks, moved); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := updateLinkedTask(tasks, trashed); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := tasks.BulkWrite(ctx, linkOwner, []models.BulkOperation{
+		bulkUpdateOp(stale, stale.Version, "Stale"),
+		bulkUpdateOp(fresh, fresh.Version, "Fresh"),
+		{Op: models.BulkDelete, ID: trashed.ID, Version: trashed.Version},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isPreconditionFailed(results[0].Err) || !isPreconditionFailed(results[2].Err) {
+		t.Errorf("stale operations got %v and %v, want precondition failures", results[0].Err, results[2].Err)
+	}
+	if results[1].Err != nil || results[1].Task.Version != fresh.Version+1 {
+		t.Errorf("fresh update got %+v, want version %d", results[1], fresh.Version+1)
+	}
+
+	got, err := tasks.GetTask(ctx, strconv.Itoa(stale.ID), linkOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Moved on" {
+		t.Errorf("stale update overwrote the task: %+v", got)
+	}
+	if _, err := tasks.GetTask(ctx, strconv.Itoa(trashed.ID), linkOwner); err != nil {
+		t.Errorf("stale delete trashed the task: %v", err)
+	}
+}
+
+func TestBulkWriteVersionRace(t *testing.T) {
+	ctx := context.Background()
+	tasks := NewMemoryTaskRepository(false, NewMemoryUserRepository())
+	task := addLinkedTask(t, tasks, 0)
+
+	// every writer saw version 1, so exactly one of them may win
+	const writers = 8
+	errs := make([]error, writers)
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				single := task
+				single.Title = "Single " + strconv.Itoa(i)
+				_, errs[i] = updateLinkedTask(tasks, single)
+				return
+			}
+			results, err := tasks.BulkWrite(ctx, linkOwner, []models.BulkOperation{bulkUpdateOp(task, task.Version, "Bulk "+strconv.Itoa(i))})
+			if err == nil {
+				err = results[0].Err
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	won := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case !isPreconditionFailed(err):
+			t.Errorf("writer %d got %v, want a precondition failure", i, err)
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d writers won, want 1", won)
+	}
+	got, err := tasks.GetTask(ctx, strconv.Itoa(task.ID), linkOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != task.Version+1 {
+		t.Errorf("version is %d, want %d", got.Version, task.Version+1)
+	}
+}
+
+func TestBulkDeleteBumpsVersion(t *testing.T) {
+	ctx := context.Background()
+	tasks := NewMemoryTaskRepository(false, NewMemoryUserRepository())
+	task := addLinkedTask(t, tasks, 0)
+
+	results, err := tasks.BulkWrite(ctx, linkOwner, []models.BulkOperation{{Op: models.BulkDelete, ID: task.ID, Version: task.Version}})
+	if err != nil || results[0].Err != nil {
+		t.Fatal(err, results[0].Err)
+	}
+
+	id := strconv.Itoa(task.ID)
+	if _, err := tasks.RestoreDeletedTask(ctx, id, linkOwner, task.Version); !isPreconditionFailed(err) {
+		t.Errorf("restoring the version from before the delete got %v, want a precondition failure", err)
+	}
+	restored, err := tasks.RestoreDeletedTask(ctx, id, linkOwner, task.Version+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Version != task.Version+2 {
+		t.Errorf("restored version is %d, want %d", restored.Version, task.Version+2)
+	}
+}
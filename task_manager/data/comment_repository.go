@@ -0,0 +1,86 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"task_manager/customError"
+	"task_manager/models"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxCommentLength bounds the body of a comment, in characters.
+const MaxCommentLength = 10000
+
+// CommentRepository stores the comments of tasks. It does not know who
+// may see a task, so callers must check that the task is visible to the
+// actor first. Like task writes, edits and deletes take the version the
+// client last saw and fail with a PreconditionFailedError if the comment
+// has changed since.
+type CommentRepository interface {
+	// AddComment stores a new comment and returns it with its ID, creation
+	// time and version set.
+	AddComment(ctx context.Context, comment models.Comment) (models.Comment, error)
+	// GetComments returns a page of the comments of a task, oldest first,
+	// without their edits.
+	GetComments(ctx context.Context, taskID, offset, limit int) (models.CommentPage, error)
+	GetComment(ctx context.Context, taskID, commentID int) (models.Comment, error)
+	// UpdateComment replaces the body of a comment and keeps the old one in
+	// its edits.
+	UpdateComment(ctx context.Context, taskID, commentID, version int, body string) (models.Comment, error)
+	DeleteComment(ctx context.Context, taskID, commentID, version int) error
+	// DeleteTaskComments removes every comment of the given tasks, once
+	// they have been purged, and returns how many it removed.
+	DeleteTaskComments(ctx context.Context, taskIDs []int) (int64, error)
+}
+
+// normalizeCommentBody trims the body of a comment and checks its length.
+func normalizeCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	invalid := &customError.ValidationError{}
+	if body == "" {
+		invalid.Add("body", "can not be empty")
+	}
+	if utf8.RuneCountInString(body) > MaxCommentLength {
+		invalid.Add("body", fmt.Sprintf("can not be longer than %d characters", MaxCommentLength))
+	}
+	return body, invalid.OrNil()
+}
+
+// editComment returns comment with its body replaced by body, or false if
+// the body is unchanged.
+func editComment(comment models.Comment, body string, now time.Time) (models.Comment, bool) {
+	if body == comment.Body {
+		return comment, false
+	}
+	comment.Edits = append(comment.Edits, models.CommentEdit{Body: comment.Body, ReplacedAt: now})
+	comment.Body = body
+	comment.EditedAt = &now
+	comment.Version++
+	return comment, true
+}
+
+func commentNotFoundError(commentID int) error {
+	return &customError.NotFoundError{Resource: "Comment", ID: commentID}
+}
+
+// checkCommentVersion is checkVersion for comments.
+func checkCommentVersion(comment models.Comment, version int) error {
+	if version != AnyVersion && comment.Version != version {
+		return staleCommentError(comment.ID)
+	}
+	return nil
+}
+
+func staleCommentError(commentID int) error {
+	return &customError.PreconditionFailedError{Reason: fmt.Sprintf("Comment with ID %d has been modified since it was fetched", commentID)}
+}
+
+// concurrentCommentWriteError is concurrentWriteError for comments.
+func concurrentCommentWriteError(commentID, version int) error {
+	if version != AnyVersion {
+		return staleCommentError(commentID)
+	}
+	return &customError.ConflictError{Reason: fmt.Sprintf("Comment with ID %d was modified concurrently, please retry", commentID)}
+}
@@ -0,0 +1,276 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"task_manager/customError"
+	"task_manager/models"
+)
+
+// MaxGraphTasks bounds the number of tasks returned by GetTaskGraph.
+const MaxGraphTasks = 500
+
+// taskLinks looks up the live tasks of one owner for the dependency rules,
+// which are shared by both backends. Tasks in the trash are left out, so
+// they neither block nor appear in graphs until they are restored. The
+// owner is matched exactly rather than as an owner scope, so tasks without
+// an owner (0, the same as AllOwners) only link among themselves.
+type taskLinks interface {
+	// linkedTasks returns the tasks with the given IDs that exist.
+	linkedTasks(ctx context.Context, ownerID int, ids []int) (map[int]models.Task, error)
+	// linkingTasks returns the tasks that are subtasks of, or blocked by,
+	// one of the given tasks.
+	linkingTasks(ctx context.Context, ownerID int, ids []int) ([]models.Task, error)
+}
+
+// checkLinks enforces the rules for the parent and blockers of task, about
+// to be written over oldTask (nil for new tasks): new links must point to
+// existing tasks of the same owner and must not close a cycle, and the task
+// can not be closed while one of its blockers is still open. Links
+// the task already had are not checked again, so a write never fails
+// because a linked task has since been deleted.
+//
+// Two concurrent writes to different tasks can still close a cycle between
+// them; graphs are walked with a visited set so this does no harm.
+func checkLinks(ctx context.Context, links taskLinks, oldTask *models.Task, task models.Task) error {
+	var oldParent int
+	var oldBlockers []int
+	wasClosed := false
+	if oldTask != nil {
+		oldParent, oldBlockers = oldTask.ParentID, oldTask.BlockedBy
+		wasClosed = oldTask.Status.IsClosed()
+	}
+
+	newParent := 0
+	if task.ParentID != oldParent {
+		newParent = task.ParentID
+	}
+	var newBlockers []int
+	for _, id := range task.BlockedBy {
+		if !slices.Contains(oldBlockers, id) {
+			newBlockers = append(newBlockers, id)
+		}
+	}
+	closing := task.Status.IsClosed() && !wasClosed
+
+	invalid := &customError.ValidationError{}
+	if newParent != 0 {
+		if err := checkParent(ctx, links, task, invalid); err != nil {
+			return err
+		}
+	}
+	if len(newBlockers) > 0 {
+		if err := checkBlockers(ctx, links, task, newBlockers, invalid); err != nil {
+			return err
+		}
+	}
+	if err := invalid.OrNil(); err != nil {
+		return err
+	}
+
+	if closing && len(task.BlockedBy) > 0 {
+		blockers, err := links.linkedTasks(ctx, task.OwnerID, task.BlockedBy)
+		if err != nil {
+			return err
+		}
+		var open []string
+		for _, id := range task.BlockedBy {
+			if blocker, ok := blockers[id]; ok && !blocker.Status.IsClosed() {
+				open = append(open, fmt.Sprint(id))
+			}
+		}
+		if len(open) > 0 {
+			return &customError.ConflictError{Reason: fmt.Sprintf(
+				"Task with ID %d can not be closed while blocked by open tasks %s", task.ID, strings.Join(open, ", "))}
+		}
+	}
+	return nil
+}
+
+// checkParent walks up from the new parent of task, which must exist and
+// must not be the task itself or one of its subtasks.
+func checkParent(ctx context.Context, links taskLinks, task models.Task, invalid *customError.ValidationError) error {
+	if task.ParentID < 0 || task.ParentID == task.ID {
+		invalid.Add("parent_id", "must be another task's ID")
+		return nil
+	}
+
+	seen := map[int]bool{}
+	for id := task.ParentID; id != 0 && !seen[id]; {
+		if id == task.ID {
+			invalid.Add("parent_id", fmt.Sprintf("task %d is a subtask of this task", task.ParentID))
+			return nil
+		}
+		seen[id] = true
+
+		found, err := links.linkedTasks(ctx, task.OwnerID, []int{id})
+		if err != nil {
+			return err
+		}
+		parent, ok := found[id]
+		if !ok {
+			if id == task.ParentID {
+				invalid.Add("parent_id", fmt.Sprintf("task %d does not exist", id))
+			}
+			return nil
+		}
+		id = parent.ParentID
+	}
+	return nil
+}
+
+// checkBlockers checks the blockers added to task: they must exist and
+// must not themselves be blocked, directly or through other tasks, by it.
+func checkBlockers(ctx context.Context, links taskLinks, task models.Task, added []int, invalid *customError.ValidationError) error {
+	for _, id := range added {
+		if id <= 0 || id == task.ID {
+			invalid.Add("blocked_by", fmt.Sprintf("%d is not another task's ID", id))
+		}
+	}
+
+	found, err := links.linkedTasks(ctx, task.OwnerID, added)
+	if err != nil {
+		return err
+	}
+	for _, id := range added {
+		if _, ok := found[id]; !ok && id > 0 && id != task.ID {
+			invalid.Add("blocked_by", fmt.Sprintf("task %d does not exist", id))
+		}
+	}
+	if task.ID == 0 {
+		// nothing can be blocked by a task that does not exist yet
+		return nil
+	}
+
+	// walk the blockers of the blockers; reaching the task closes a cycle
+	blockedVia := map[int]int{} // task -> the added blocker it was reached from
+	for id := range found {
+		blockedVia[id] = id
+	}
+	frontier := found
+	for len(frontier) > 0 {
+		var next []int
+		for _, blocker := range frontier {
+			for _, id := range blocker.BlockedBy {
+				if id == task.ID {
+					invalid.Add("blocked_by", fmt.Sprintf("task %d is already blocked by this task", blockedVia[blocker.ID]))
+					return nil
+				}
+				if _, seen := blockedVia[id]; !seen {
+					blockedVia[id] = blockedVia[blocker.ID]
+					next = append(next, id)
+				}
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		if frontier, err = links.linkedTasks(ctx, task.OwnerID, next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildTaskGraph collects the tasks connected to root, walking one level
+// of parents, subtasks, blockers and blocked tasks at a time.
+func buildTaskGraph(ctx context.Context, links taskLinks, root models.Task) (models.TaskGraph, error) {
+	graph := models.TaskGraph{TaskID: root.ID, Links: []models.TaskLink{}}
+	tasks := map[int]models.Task{root.ID: root}
+	frontier := []int{root.ID}
+
+	for len(frontier) > 0 {
+		var referenced []int
+		for _, id := range frontier {
+			task := tasks[id]
+			for _, linked := range append([]int{task.ParentID}, task.BlockedBy...) {
+				if _, seen := tasks[linked]; linked != 0 && !seen {
+					referenced = append(referenced, linked)
+				}
+			}
+		}
+
+		up, err := links.linkedTasks(ctx, root.OwnerID, referenced)
+		if err != nil {
+			return models.TaskGraph{}, err
+		}
+		down, err := links.linkingTasks(ctx, root.OwnerID, frontier)
+		if err != nil {
+			return models.TaskGraph{}, err
+		}
+
+		frontier = nil
+		for _, task := range append(down, mapValues(up)...) {
+			if _, seen := tasks[task.ID]; seen {
+				continue
+			}
+			if len(tasks) == MaxGraphTasks {
+				graph.Truncated = true
+				break
+			}
+			tasks[task.ID] = task
+			frontier = append(frontier, task.ID)
+		}
+		if graph.Truncated {
+			break
+		}
+	}
+
+	for _, task := range tasks {
+		graph.Tasks = append(graph.Tasks, task)
+		if _, ok := tasks[task.ParentID]; ok {
+			graph.Links = append(graph.Links, models.TaskLink{From: task.ParentID, To: task.ID, Type: models.LinkSubtask})
+		}
+		for _, blocker := range task.BlockedBy {
+			if _, ok := tasks[blocker]; ok {
+				graph.Links = append(graph.Links, models.TaskLink{From: blocker, To: task.ID, Type: models.LinkBlocks})
+			}
+		}
+	}
+	slices.SortFunc(graph.Tasks, func(a, b models.Task) int { return a.ID - b.ID })
+	slices.SortFunc(graph.Links, func(a, b models.TaskLink) int {
+		if a.From != b.From {
+			return a.From - b.From
+		}
+		if a.To != b.To {
+			return a.To - b.To
+		}
+		return strings.Compare(string(a.Type), string(b.Type))
+	})
+	return graph, nil
+}
+
+func mapValues(tasks map[int]models.Task) []models.Task {
+	values := make([]models.Task, 0, len(tasks))
+	for _, task := range tasks {
+		values = append(values, task)
+	}
+	return values
+}
+
+// unlinkTask drops the links of task to any of the purged tasks and bumps
+// its version if that changed it.
+func unlinkTask(task models.Task, purged []models.Task) (models.Task, bool) {
+	isPurged := func(id int) bool {
+		return slices.ContainsFunc(purged, func(p models.Task) bool { return p.ID == id })
+	}
+
+	changed := false
+	if task.ParentID != 0 && isPurged(task.ParentID) {
+		task.ParentID = 0
+		changed = true
+	}
+	if slices.ContainsFunc(task.BlockedBy, isPurged) {
+		task.BlockedBy = slices.DeleteFunc(slices.Clone(task.BlockedBy), isPurged)
+		if len(task.BlockedBy) == 0 {
+			task.BlockedBy = nil
+		}
+		changed = true
+	}
+	if changed {
+		task.Version++
+	}
+	return task, changed
+}
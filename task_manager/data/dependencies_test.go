@@ -0,0 +1,141 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"task_manager/customError"
+	"task_manager/models"
+	"testing"
+	"time"
+)
+
+var linkOwner = models.Actor{UserID: 1}
+
+func addLinkedTask(t *testing.T, tasks *MemoryTaskRepository, parentID int, blockedBy ...int) models.Task {
+	t.Helper()
+	task, err := tasks.AddATask(context.Background(), models.Task{
+		Title:       "Task",
+		Description: "Linked",
+		DueDate:     time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond),
+		Status:      models.Pending,
+		OwnerID:     linkOwner.UserID,
+		ParentID:    parentID,
+		BlockedBy:   blockedBy,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+func updateLinkedTask(tasks *MemoryTaskRepository, task models.Task) (models.Task, error) {
+	return tasks.UpdateTask(context.Background(), strconv.Itoa(task.ID), linkOwner, task.Version, task)
+}
+
+func TestCheckLinksRejectsCycles(t *testing.T) {
+	tests := []struct {
+		name string
+		link func(a, c models.Task) models.Task
+	}{
+		{"blocked by a task it blocks", func(a, c models.Task) models.Task {
+			a.BlockedBy = []int{c.ID}
+			return a
+		}},
+		{"subtask of its own subtask", func(a, c models.Task) models.Task {
+			a.ParentID = c.ID
+			return a
+		}},
+		{"blocked by itself", func(a, c models.Task) models.Task {
+			a.BlockedBy = []int{a.ID}
+			return a
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := NewMemoryTaskRepository(false, NewMemoryUserRepository())
+			a := addLinkedTask(t, tasks, 0)
+			b := addLinkedTask(t, tasks, a.ID, a.ID)
+			c := addLinkedTask(t, tasks, b.ID, b.ID)
+
+			_, err := updateLinkedTask(tasks, tt.link(a, c))
+			var invalid *customError.ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("got %v, want a validation error", err)
+			}
+		})
+	}
+}
+
+func TestCheckLinksKeepsBlockedTasksOpen(t *testing.T) {
+	t.Cleanup(func() {
+		if err := SetWorkflow(models.DefaultWorkflow()); err != nil {
+			t.Fatal(err)
+		}
+	})
+	archiveOnly := models.DefaultWorkflow()
+	archiveOnly.Closed = append(archiveOnly.Closed[:0], models.Archived)
+	archiveOnly.Transitions[models.Pending] = append(archiveOnly.Transitions[models.Pending], models.Archived)
+
+	tests := []struct {
+		name     string
+		workflow models.Workflow
+		status   string
+		blocked  bool
+	}{
+		{"default workflow completing", models.DefaultWorkflow(), "Completed", true},
+		{"default workflow starting", models.DefaultWorkflow(), "InProgress", false},
+		{"custom closed status", archiveOnly, "Archived", true},
+		{"status the workflow leaves open", archiveOnly, "Completed", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetWorkflow(tt.workflow); err != nil {
+				t.Fatal(err)
+			}
+			tasks := NewMemoryTaskRepository(false, NewMemoryUserRepository())
+			blocker := addLinkedTask(t, tasks, 0)
+			task := addLinkedTask(t, tasks, 0, blocker.ID)
+
+			task.Status = models.Status(tt.status)
+			_, err := updateLinkedTask(tasks, task)
+			var conflict *customError.ConflictError
+			if blocked := errors.As(err, &conflict); blocked != tt.blocked || (!blocked && err != nil) {
+				t.Fatalf("got %v, want blocked %v", err, tt.blocked)
+			}
+			if !tt.blocked {
+				return
+			}
+
+			blocker.Status = models.Status(tt.status)
+			if _, err := updateLinkedTask(tasks, blocker); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := updateLinkedTask(tasks, task); err != nil {
+				t.Fatalf("closing once the blocker is closed: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckLinksStaysWithinTheOwner(t *testing.T) {
+	tasks := NewMemoryTaskRepository(false, NewMemoryUserRepository())
+	owned := addLinkedTask(t, tasks, 0)
+
+	// owner 0 is also AllOwners, which must not widen the lookup
+	for _, ownerID := range []int{0, 2} {
+		_, err := tasks.AddATask(context.Background(), models.Task{
+			Title:       "Task",
+			Description: "Of another owner",
+			DueDate:     time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond),
+			Status:      models.Pending,
+			OwnerID:     ownerID,
+			ParentID:    owned.ID,
+			BlockedBy:   []int{owned.ID},
+		})
+		var invalid *customError.ValidationError
+		if !errors.As(err, &invalid) || len(invalid.Fields) != 2 {
+			t.Errorf("owner %d linking to a task of owner %d got %v, want parent_id and blocked_by rejected", ownerID, owned.OwnerID, err)
+		}
+	}
+}
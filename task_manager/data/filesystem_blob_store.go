@@ -0,0 +1,86 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FilesystemBlobStore keeps every blob in a file named after its key in
+// one directory. Its operations do not use the contexts they take.
+type FilesystemBlobStore struct {
+	dir string
+}
+
+// NewFilesystemBlobStore stores blobs in dir, creating it if needed.
+func NewFilesystemBlobStore(dir string) (*FilesystemBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create attachment directory: %w", err)
+	}
+	return &FilesystemBlobStore{dir: dir}, nil
+}
+
+// path maps key to its file, refusing keys that would leave the directory.
+func (s *FilesystemBlobStore) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || key == "." || key == ".." {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+// Put writes to a temporary file first and renames it into place, so a
+// failed upload never leaves a partial blob behind.
+func (s *FilesystemBlobStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, "."+key+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed to store blob %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to store blob %s: %w", key, err)
+	}
+	return size, nil
+}
+
+func (s *FilesystemBlobStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob %s: %w", key, err)
+	}
+	return file, nil
+}
+
+func (s *FilesystemBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"task_manager/customError"
+	"task_manager/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFixtures stores the tasks listed in a JSON or YAML file (chosen by
+// its extension) under their own IDs and returns how many were created or
+// changed. Tasks already stored with the same values are left alone, so
+// loading a file twice has no further effect.
+func LoadFixtures(ctx context.Context, tasks TaskRepository, path string) (int, error) {
+	fixtures, err := readFixtures(path)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for i, fixture := range fixtures {
+		result, err := tasks.PutTask(ctx, fixture)
+		if err != nil {
+			return changed, fmt.Errorf("fixture %d in %s: %w", i+1, path, err)
+		}
+		if result.Written {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// readFixtures parses a list of tasks. YAML is converted to JSON first so
+// both formats accept the same fields and due date formats.
+func readFixtures(path string) ([]models.Task, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures file: %w", err)
+	}
+
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		var list []any
+		if err := yaml.Unmarshal(raw, &list); err != nil {
+			return nil, fmt.Errorf("failed to parse fixtures file %s: %w", path, err)
+		}
+		if raw, err = json.Marshal(list); err != nil {
+			return nil, fmt.Errorf("failed to convert fixtures file %s: %w", path, err)
+		}
+	}
+
+	var fixtures []models.Task
+	if err := json.Unmarshal(raw, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures file %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// validateFixture checks a task written by PutTask. Unlike new tasks it
+// may be in any known status, as fixtures describe existing data.
+func validateFixture(task models.Task) error {
+	invalid := taskFieldErrors(task)
+	if task.ID <= 0 {
+		invalid.Add("id", "must be a positive number")
+	}
+	return invalid.OrNil()
+}
+
+// putTaskVersion prepares task for storing over existing (nil if there is
+// none): it fills in the version, keeps the attachments, whose bytes a
+// fixture can not supply, and reports whether anything changed.
+func putTaskVersion(existing *models.Task, task *models.Task) (bool, error) {
+	task.DeletedAt = nil
+	if existing == nil {
+		task.Version = 1
+		task.Attachments = nil
+		return true, nil
+	}
+
+	task.Version = existing.Version
+	task.Attachments = existing.Attachments
+	changed, err := changedTaskFields(*existing, *task)
+	if err != nil {
+		return false, err
+	}
+	if len(changed) == 0 {
+		return false, nil
+	}
+	task.Version++
+	return true, nil
+}
+
+func fixtureConflictError(taskID int) error {
+	return &customError.ConflictError{Reason: fmt.Sprintf("Task with ID %d was modified while loading fixtures", taskID)}
+}
@@ -0,0 +1,83 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"task_manager/models"
+	"testing"
+)
+
+func writeFixtures(t *testing.T, path, yaml string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// historyActions lists the action, version and actor of every history
+// entry of the task.
+func historyActions(t *testing.T, history HistoryRepository, taskID int) []string {
+	t.Helper()
+	entries, err := history.GetTaskHistory(context.Background(), taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, fmt.Sprintf("%s v%d by %d", entry.Action, entry.Version, entry.ActorID))
+	}
+	return actions
+}
+
+func TestLoadFixturesRecordsHistory(t *testing.T) {
+	ctx := context.Background()
+	history := NewMemoryHistoryRepository()
+	tasks := NewAuditedTaskRepository(NewMemoryTaskRepository(false, NewMemoryUserRepository()), history)
+	path := filepath.Join(t.TempDir(), "tasks.yaml")
+
+	writeFixtures(t, path, `
+- {id: 1, title: Prepare demo, description: Walk through it, due_date: 2025-09-01, status: Pending, owner_id: 1}
+- {id: 2, title: Book room, description: For the demo, due_date: 2025-09-01, status: Pending, owner_id: 1}
+`)
+	if changed, err := LoadFixtures(ctx, tasks, path); err != nil || changed != 2 {
+		t.Fatalf("first load changed %d tasks (%v), want 2", changed, err)
+	}
+
+	// task 2 goes to the trash, then the file changes task 1 and puts 2 back
+	if err := tasks.DeleteTask(ctx, "2", models.Actor{UserID: 1}, AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	writeFixtures(t, path, `
+- {id: 1, title: Prepare the demo, description: Walk through it, due_date: 2025-09-01, status: Pending, owner_id: 1}
+- {id: 2, title: Book room, description: For the demo, due_date: 2025-09-01, status: Pending, owner_id: 1}
+`)
+	for load, want := range []int{2, 0} {
+		if changed, err := LoadFixtures(ctx, tasks, path); err != nil || changed != want {
+			t.Fatalf("reload %d changed %d tasks (%v), want %d", load+1, changed, err, want)
+		}
+	}
+
+	tests := []struct {
+		taskID int
+		want   []string
+	}{
+		{1, []string{"create v1 by 0", "update v2 by 0"}},
+		{2, []string{"create v1 by 0", "delete v2 by 1", "update v3 by 0"}},
+	}
+	for _, tt := range tests {
+		if got := historyActions(t, history, tt.taskID); !slices.Equal(got, tt.want) {
+			t.Errorf("task %d has history %v, want %v", tt.taskID, got, tt.want)
+		}
+	}
+
+	entries, err := history.GetTaskHistory(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := entries[1].Changes; len(changes) != 1 || changes[0].Field != "title" {
+		t.Errorf("fixture update recorded changes %+v, want only the title", changes)
+	}
+}
@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// GridFSBlobStore keeps blobs in the "attachments" GridFS bucket, using
+// the key as both the file ID and its name.
+type GridFSBlobStore struct {
+	bucket *mongo.GridFSBucket
+}
+
+func NewGridFSBlobStore(db *mongo.Database) *GridFSBlobStore {
+	return &GridFSBlobStore{bucket: db.GridFSBucket(options.GridFSBucket().SetName("attachments"))}
+}
+
+func (s *GridFSBlobStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	counted := &countingReader{r: r}
+	if err := s.bucket.UploadFromStreamWithID(ctx, key, key, counted); err != nil {
+		return 0, wrapMongoError(err, "failed to store blob %s", key)
+	}
+	return counted.n, nil
+}
+
+func (s *GridFSBlobStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	stream, err := s.bucket.OpenDownloadStream(ctx, key)
+	if err != nil {
+		return nil, wrapMongoError(err, "failed to open blob %s", key)
+	}
+	return &gridFSBlob{bucket: s.bucket, ctx: ctx, key: key, stream: stream, size: stream.GetFile().Length}, nil
+}
+
+func (s *GridFSBlobStore) Delete(ctx context.Context, key string) error {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	if err := s.bucket.Delete(ctx, key); err != nil && !errors.Is(err, mongo.ErrFileNotFound) {
+		return wrapMongoError(err, "failed to delete blob %s", key)
+	}
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// gridFSBlob makes a GridFS download seekable. Download streams can only
+// move forward, so seeking closes the stream and the next read opens a
+// new one, skipping to the offset.
+type gridFSBlob struct {
+	bucket *mongo.GridFSBucket
+	ctx    context.Context
+	key    string
+	stream *mongo.GridFSDownloadStream // nil until the next read
+	offset int64
+	size   int64
+}
+
+func (b *gridFSBlob) Read(p []byte) (int, error) {
+	if b.offset >= b.size {
+		return 0, io.EOF
+	}
+	if b.stream == nil {
+		stream, err := b.bucket.OpenDownloadStream(b.ctx, b.key)
+		if err != nil {
+			return 0, wrapMongoError(err, "failed to open blob %s", b.key)
+		}
+		b.stream = stream
+		if _, err := stream.Skip(b.offset); err != nil {
+			return 0, wrapMongoError(err, "failed to read blob %s", b.key)
+		}
+	}
+	n, err := b.stream.Read(p)
+	b.offset += int64(n)
+	return n, err
+}
+
+func (b *gridFSBlob) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.offset
+	case io.SeekEnd:
+		offset += b.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("seek before the start of the blob")
+	}
+	if offset != b.offset && b.stream != nil {
+		_ = b.stream.Close()
+		b.stream = nil
+	}
+	b.offset = offset
+	return offset, nil
+}
+
+func (b *gridFSBlob) Close() error {
+	if b.stream == nil {
+		return nil
+	}
+	err := b.stream.Close()
+	b.stream = nil
+	return err
+}
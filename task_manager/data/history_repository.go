@@ -0,0 +1,13 @@
+package data
+
+import (
+	"context"
+	"task_manager/models"
+)
+
+// HistoryRepository stores the audit trail of task writes.
+type HistoryRepository interface {
+	AddEntry(ctx context.Context, entry models.TaskHistoryEntry) error
+	// GetTaskHistory returns a task's entries, oldest first.
+	GetTaskHistory(ctx context.Context, taskID int) ([]models.TaskHistoryEntry, error)
+}
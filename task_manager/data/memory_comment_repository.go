@@ -0,0 +1,137 @@
+package data
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"task_manager/models"
+	"time"
+)
+
+type MemoryCommentRepository struct {
+	mu       sync.RWMutex
+	comments map[int]models.Comment
+	lastID   int
+}
+
+func NewMemoryCommentRepository() *MemoryCommentRepository {
+	return &MemoryCommentRepository{comments: map[int]models.Comment{}}
+}
+
+func (r *MemoryCommentRepository) AddComment(ctx context.Context, comment models.Comment) (models.Comment, error) {
+	body, err := normalizeCommentBody(comment.Body)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	comment = models.Comment{
+		ID:        r.lastID,
+		TaskID:    comment.TaskID,
+		AuthorID:  comment.AuthorID,
+		Body:      body,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+		Version:   1,
+	}
+	r.comments[comment.ID] = comment
+	return comment, nil
+}
+
+func (r *MemoryCommentRepository) GetComments(ctx context.Context, taskID, offset, limit int) (models.CommentPage, error) {
+	offset, limit, err := normalizePage(offset, limit)
+	if err != nil {
+		return models.CommentPage{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []models.Comment
+	for _, comment := range r.comments {
+		if comment.TaskID == taskID {
+			comment.Edits = nil
+			matched = append(matched, comment)
+		}
+	}
+	slices.SortFunc(matched, func(a, b models.Comment) int { return a.ID - b.ID })
+
+	page := models.CommentPage{Comments: []models.Comment{}, Total: int64(len(matched)), Offset: offset, Limit: limit}
+	if offset < len(matched) {
+		page.Comments = matched[offset:min(offset+limit, len(matched))]
+	}
+	return page, nil
+}
+
+func (r *MemoryCommentRepository) GetComment(ctx context.Context, taskID, commentID int) (models.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.comment(taskID, commentID)
+}
+
+// comment returns a comment of the given task. Callers must hold the lock.
+func (r *MemoryCommentRepository) comment(taskID, commentID int) (models.Comment, error) {
+	comment, ok := r.comments[commentID]
+	if !ok || comment.TaskID != taskID {
+		return models.Comment{}, commentNotFoundError(commentID)
+	}
+	comment.Edits = slices.Clone(comment.Edits)
+	return comment, nil
+}
+
+func (r *MemoryCommentRepository) UpdateComment(ctx context.Context, taskID, commentID, version int, body string) (models.Comment, error) {
+	body, err := normalizeCommentBody(body)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, err := r.comment(taskID, commentID)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if err := checkCommentVersion(comment, version); err != nil {
+		return models.Comment{}, err
+	}
+
+	comment, changed := editComment(comment, body, time.Now().UTC().Truncate(time.Millisecond))
+	if changed {
+		r.comments[commentID] = comment
+	}
+	return comment, nil
+}
+
+func (r *MemoryCommentRepository) DeleteComment(ctx context.Context, taskID, commentID, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, err := r.comment(taskID, commentID)
+	if err != nil {
+		return err
+	}
+	if err := checkCommentVersion(comment, version); err != nil {
+		return err
+	}
+
+	delete(r.comments, commentID)
+	return nil
+}
+
+func (r *MemoryCommentRepository) DeleteTaskComments(ctx context.Context, taskIDs []int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, comment := range r.comments {
+		if slices.Contains(taskIDs, comment.TaskID) {
+			delete(r.comments, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,33 @@
+package data
+
+import (
+	"context"
+	"sync"
+	"task_manager/models"
+)
+
+type MemoryHistoryRepository struct {
+	mu      sync.RWMutex
+	entries map[int][]models.TaskHistoryEntry
+}
+
+func NewMemoryHistoryRepository() *MemoryHistoryRepository {
+	return &MemoryHistoryRepository{entries: map[int][]models.TaskHistoryEntry{}}
+}
+
+func (r *MemoryHistoryRepository) AddEntry(ctx context.Context, entry models.TaskHistoryEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[entry.TaskID] = append(r.entries[entry.TaskID], entry)
+	return nil
+}
+
+func (r *MemoryHistoryRepository) GetTaskHistory(ctx context.Context, taskID int) ([]models.TaskHistoryEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history := make([]models.TaskHistoryEntry, len(r.entries[taskID]))
+	copy(history, r.entries[taskID])
+	return history, nil
+}
@@ -0,0 +1,108 @@
+package data
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"task_manager/models"
+	"time"
+)
+
+type MemoryReminderRepository struct {
+	mu         sync.RWMutex
+	deliveries map[int]models.ReminderDelivery
+	lastID     int
+}
+
+func NewMemoryReminderRepository() *MemoryReminderRepository {
+	return &MemoryReminderRepository{deliveries: map[int]models.ReminderDelivery{}}
+}
+
+func (r *MemoryReminderRepository) AddDelivery(ctx context.Context, delivery models.ReminderDelivery) (models.ReminderDelivery, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.deliveries {
+		if sameReminder(stored, delivery) {
+			return stored, false, nil
+		}
+	}
+
+	r.lastID++
+	delivery = newDelivery(delivery)
+	delivery.ID = r.lastID
+	r.deliveries[delivery.ID] = delivery
+	return delivery, true, nil
+}
+
+func (r *MemoryReminderRepository) ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.ReminderDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var claimed []models.ReminderDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == models.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			claimed = append(claimed, delivery)
+		}
+	}
+	slices.SortFunc(claimed, func(a, b models.ReminderDelivery) int {
+		if c := a.NextAttemptAt.Compare(b.NextAttemptAt); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+	if len(claimed) > limit {
+		claimed = claimed[:limit]
+	}
+
+	for i := range claimed {
+		claimed[i].Attempts++
+		claimed[i].NextAttemptAt = leaseUntil
+		r.deliveries[claimed[i].ID] = claimed[i]
+	}
+	return claimed, nil
+}
+
+func (r *MemoryReminderRepository) UpdateDelivery(ctx context.Context, delivery models.ReminderDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.deliveries[delivery.ID]
+	if !ok {
+		return deliveryNotFoundError(delivery.ID)
+	}
+	stored.Status = delivery.Status
+	stored.NextAttemptAt = delivery.NextAttemptAt
+	stored.LastError = delivery.LastError
+	stored.SentAt = delivery.SentAt
+	r.deliveries[delivery.ID] = stored
+	return nil
+}
+
+func (r *MemoryReminderRepository) GetTaskDeliveries(ctx context.Context, taskID int) ([]models.ReminderDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := []models.ReminderDelivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.TaskID == taskID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	slices.SortFunc(deliveries, func(a, b models.ReminderDelivery) int { return a.ID - b.ID })
+	return deliveries, nil
+}
+
+func (r *MemoryReminderRepository) DeleteTaskDeliveries(ctx context.Context, taskIDs []int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, delivery := range r.deliveries {
+		if slices.Contains(taskIDs, delivery.TaskID) {
+			delete(r.deliveries, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,724 @@
+package data
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"task_manager/customError"
+	"task_manager/models"
+	"time"
+)
+
+// MemoryTaskRepository keeps tasks in a map guarded by a RWMutex. It is
+// meant for local development and tests where no MongoDB is available.
+// Its operations never block, so the contexts they take are unused.
+type MemoryTaskRepository struct {
+	mu               sync.RWMutex
+	tasks            map[int]models.Task
+	lastID           int
+	lastAttachmentID int
+	users            UserRepository
+}
+
+// NewMemoryTaskRepository starts out empty, or with the sample task if seed
+// is set. users is used to check the assignees and watchers of tasks.
+func NewMemoryTaskRepository(seed bool, users UserRepository) *MemoryTaskRepository {
+	repo := &MemoryTaskRepository{tasks: map[int]models.Task{}, users: users}
+	if seed {
+		task := seedTask()
+		repo.tasks[task.ID] = task
+		repo.lastID = task.ID
+	}
+	return repo
+}
+
+func (r *MemoryTaskRepository) GetAllTasks(ctx context.Context, query models.TaskQuery) (models.TaskPage, error) {
+	query, err := normalizeTaskQuery(query)
+	if err != nil {
+		return models.TaskPage{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched := make([]*models.Task, 0, len(r.tasks))
+	for _, task := range r.tasks {
+		if !matchesTaskQuery(task, query) {
+			continue
+		}
+		task := task
+		matched = append(matched, &task)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return lessTask(matched[i], matched[j], query.SortBy, query.SortDesc)
+	})
+
+	page := models.TaskPage{
+		Tasks:  []*models.Task{},
+		Total:  int64(len(matched)),
+		Offset: query.Offset,
+		Limit:  query.Limit,
+	}
+	if query.Offset < len(matched) {
+		end := min(query.Offset+query.Limit, len(matched))
+		page.Tasks = matched[query.Offset:end]
+	}
+	return page, nil
+}
+
+// EachTask sorts a snapshot of the matching tasks and releases the lock
+// before calling fn, so a slow consumer does not hold up writers.
+func (r *MemoryTaskRepository) EachTask(ctx context.Context, query models.TaskQuery, fn func(models.Task) error) error {
+	query, err := normalizeTaskQuery(query)
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	matched := make([]*models.Task, 0, len(r.tasks))
+	for _, task := range r.tasks {
+		if matchesTaskQuery(task, query) {
+			task := task
+			matched = append(matched, &task)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return lessTask(matched[i], matched[j], query.SortBy, query.SortDesc)
+	})
+
+	for _, task := range matched {
+		if err := fn(*task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchTasks scores every matching task with a simplified version of
+// MongoDB's text search; see searchQuery.
+func (r *MemoryTaskRepository) SearchTasks(ctx context.Context, text string, query models.TaskQuery) (models.TaskSearchPage, error) {
+	search, err := parseSearch(text)
+	if err != nil {
+		return models.TaskSearchPage{}, err
+	}
+	query, err = normalizeTaskQuery(query)
+	if err != nil {
+		return models.TaskSearchPage{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hits := []models.TaskSearchHit{}
+	for _, task := range r.tasks {
+		if !matchesTaskQuery(task, query) {
+			continue
+		}
+		if score := search.scoreTask(task); score > 0 {
+			task := task
+			hits = append(hits, models.TaskSearchHit{Task: &task, Score: score})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Task.ID < hits[j].Task.ID
+	})
+
+	page := models.TaskSearchPage{
+		Hits:   []models.TaskSearchHit{},
+		Total:  int64(len(hits)),
+		Offset: query.Offset,
+		Limit:  query.Limit,
+	}
+	if query.Offset < len(hits) {
+		end := min(query.Offset+query.Limit, len(hits))
+		page.Hits = hits[query.Offset:end]
+		for i := range page.Hits {
+			page.Hits[i].Highlights = search.highlightTask(*page.Hits[i].Task)
+		}
+	}
+	return page, nil
+}
+
+func (r *MemoryTaskRepository) GetTaskGraph(ctx context.Context, id string, actor models.Actor) (models.TaskGraph, error) {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.TaskGraph{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, err := r.liveTask(taskID, actor)
+	if err != nil {
+		return models.TaskGraph{}, err
+	}
+	return buildTaskGraph(ctx, r, task)
+}
+
+// linkedTasks implements taskLinks. Callers must hold the lock.
+func (r *MemoryTaskRepository) linkedTasks(ctx context.Context, ownerID int, ids []int) (map[int]models.Task, error) {
+	found := map[int]models.Task{}
+	for _, id := range ids {
+		if task, ok := r.tasks[id]; ok && task.DeletedAt == nil && task.OwnerID == ownerID {
+			found[id] = task
+		}
+	}
+	return found, nil
+}
+
+// linkingTasks implements taskLinks. Callers must hold the lock.
+func (r *MemoryTaskRepository) linkingTasks(ctx context.Context, ownerID int, ids []int) ([]models.Task, error) {
+	var found []models.Task
+	for _, task := range r.tasks {
+		if task.DeletedAt != nil || task.OwnerID != ownerID {
+			continue
+		}
+		if slices.Contains(ids, task.ParentID) || slices.ContainsFunc(task.BlockedBy, func(id int) bool { return slices.Contains(ids, id) }) {
+			found = append(found, task)
+		}
+	}
+	return found, nil
+}
+
+func matchesTaskQuery(task models.Task, query models.TaskQuery) bool {
+	if !ownedBy(task, query.OwnerID) || (task.DeletedAt != nil) != query.Trash {
+		return false
+	}
+	if query.Status != "" && task.Status != query.Status {
+		return false
+	}
+	if !query.DueAfter.IsZero() && task.DueDate.Before(query.DueAfter) {
+		return false
+	}
+	if !query.DueBefore.IsZero() && task.DueDate.After(query.DueBefore) {
+		return false
+	}
+	if query.Overdue && !task.IsOverdue(time.Now()) {
+		return false
+	}
+	if query.Title != "" && !strings.Contains(strings.ToLower(task.Title), strings.ToLower(query.Title)) {
+		return false
+	}
+	for _, tag := range query.AllTags {
+		if !slices.Contains(task.Tags, tag) {
+			return false
+		}
+	}
+	if query.ParentID != 0 && task.ParentID != query.ParentID {
+		return false
+	}
+	if query.AssigneeID != 0 && !slices.Contains(task.Assignees, query.AssigneeID) {
+		return false
+	}
+	if query.WatcherID != 0 && !slices.Contains(task.Watchers, query.WatcherID) {
+		return false
+	}
+	if query.Recurring && task.Recurrence == "" {
+		return false
+	}
+	if query.HasReminders && len(task.Reminders) == 0 {
+		return false
+	}
+	if len(query.AnyTags) > 0 && !slices.ContainsFunc(query.AnyTags, func(tag string) bool { return slices.Contains(task.Tags, tag) }) {
+		return false
+	}
+	return true
+}
+
+// lessTask orders tasks by the given field with id as the tie-breaker,
+// mirroring the sort the Mongo backend sends to the server.
+func lessTask(a, b *models.Task, sortBy string, desc bool) bool {
+	var cmp int
+	switch sortBy {
+	case "title":
+		cmp = strings.Compare(a.Title, b.Title)
+	case "due_date":
+		cmp = a.DueDate.Compare(b.DueDate)
+	case "status":
+		cmp = strings.Compare(string(a.Status), string(b.Status))
+	}
+	if cmp == 0 {
+		cmp = a.ID - b.ID
+	}
+	if desc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// ownedBy reports whether task is visible under the given owner scope.
+func ownedBy(task models.Task, ownerID int) bool {
+	return ownerID == AllOwners || task.OwnerID == ownerID
+}
+
+// liveTask returns the task with taskID if actor may see it and it is not
+// in the trash. Callers must hold the lock.
+func (r *MemoryTaskRepository) liveTask(taskID int, actor models.Actor) (models.Task, error) {
+	task, ok := r.tasks[taskID]
+	if !ok || !ownedBy(task, actor.OwnerScope()) || task.DeletedAt != nil {
+		return models.Task{}, &customError.NotFoundError{ID: taskID}
+	}
+	return task, nil
+}
+
+func (r *MemoryTaskRepository) GetTask(ctx context.Context, id string, actor models.Actor) (models.Task, error) {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, ok := r.tasks[taskID]
+	if !ok || !actor.CanRead(task) || task.DeletedAt != nil {
+		return models.Task{}, &customError.NotFoundError{ID: taskID}
+	}
+	return task, nil
+}
+
+func (r *MemoryTaskRepository) UpdateTask(ctx context.Context, id string, actor models.Actor, version int, updatedTask models.Task) (models.Task, error) {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if err := validateTask(updatedTask); err != nil {
+		return models.Task{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oldTask, err := r.liveTask(taskID, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return models.Task{}, err
+	}
+	if err := checkTransition(oldTask, updatedTask); err != nil {
+		return models.Task{}, err
+	}
+
+	updatedTask.ID = taskID
+	updatedTask.OwnerID = oldTask.OwnerID
+	updatedTask.Attachments = oldTask.Attachments
+	updatedTask.SeriesID, updatedTask.Occurrence = oldTask.SeriesID, oldTask.Occurrence
+	updatedTask.Version = oldTask.Version + 1
+	updatedTask.DeletedAt = nil
+	if err := checkReferences(ctx, r, r.users, &oldTask, updatedTask); err != nil {
+		return models.Task{}, err
+	}
+	r.tasks[taskID] = updatedTask
+	return updatedTask, nil
+}
+
+func (r *MemoryTaskRepository) PatchTask(ctx context.Context, id string, actor models.Actor, version int, format PatchFormat, patch []byte) (models.Task, error) {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oldTask, err := r.liveTask(taskID, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return models.Task{}, err
+	}
+
+	patchedTask, err := applyTaskPatch(oldTask, format, patch)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := validateTaskUpdate(oldTask, patchedTask); err != nil {
+		return models.Task{}, err
+	}
+	if err := checkReferences(ctx, r, r.users, &oldTask, patchedTask); err != nil {
+		return models.Task{}, err
+	}
+	changed, err := changedTaskFields(oldTask, patchedTask)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if len(changed) == 0 {
+		return patchedTask, nil
+	}
+
+	patchedTask.Version = oldTask.Version + 1
+	r.tasks[taskID] = patchedTask
+	return patchedTask, nil
+}
+
+func (r *MemoryTaskRepository) UpdateTags(ctx context.Context, id string, actor models.Actor, version int, add, remove []string) (models.Task, error) {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oldTask, err := r.liveTask(taskID, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return models.Task{}, err
+	}
+
+	task, changed := retagTask(oldTask, add, remove)
+	if !changed {
+		return task, nil
+	}
+	if err := validateTask(task); err != nil {
+		return models.Task{}, err
+	}
+
+	task.Version++
+	r.tasks[taskID] = task
+	return task, nil
+}
+
+func (r *MemoryTaskRepository) UpdateTaskUsers(ctx context.Context, id string, actor models.Actor, version int, list models.UserList, add, remove []int) (models.Task, error) {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oldTask, err := r.liveTask(taskID, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return models.Task{}, err
+	}
+
+	task, changed := updateTaskUsers(oldTask, list, add, remove)
+	if !changed {
+		return task, nil
+	}
+	if err := validateTask(task); err != nil {
+		return models.Task{}, err
+	}
+	if err := checkTaskUsers(ctx, r.users, &oldTask, task); err != nil {
+		return models.Task{}, err
+	}
+
+	task.Version++
+	r.tasks[taskID] = task
+	return task, nil
+}
+
+func (r *MemoryTaskRepository) AddAttachment(ctx context.Context, id string, actor models.Actor, version int, attachment models.Attachment) (models.Task, error) {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oldTask, err := r.liveTask(taskID, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return models.Task{}, err
+	}
+
+	attachment.ID = r.lastAttachmentID + 1
+	task, err := addAttachment(oldTask, attachment)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	r.lastAttachmentID++
+	r.tasks[taskID] = task
+	return task, nil
+}
+
+func (r *MemoryTaskRepository) RemoveAttachment(ctx context.Context, id string, actor models.Actor, version int, attachmentID int) (models.Task, error) {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oldTask, err := r.liveTask(taskID, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return models.Task{}, err
+	}
+
+	task, err := removeAttachment(oldTask, attachmentID)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	r.tasks[taskID] = task
+	return task, nil
+}
+
+func (r *MemoryTaskRepository) AdvanceSeries(ctx context.Context, head models.Task) (SeriesAdvance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oldHead, err := r.liveTask(head.ID, models.Actor{Admin: true})
+	if err != nil {
+		return SeriesAdvance{}, err
+	}
+	if err := checkVersion(oldHead, head.Version); err != nil {
+		return SeriesAdvance{}, err
+	}
+	due, ok := NextOccurrence(oldHead)
+	if !ok {
+		return SeriesAdvance{Previous: oldHead, Head: oldHead}, nil
+	}
+
+	advance := SeriesAdvance{Previous: oldHead, Head: moveRuleOff(oldHead)}
+	next := newOccurrence(oldHead, due)
+	for _, task := range r.tasks {
+		if task.SeriesID == next.SeriesID && task.Occurrence == next.Occurrence {
+			advance.Next = &task
+			break
+		}
+	}
+	if advance.Next == nil {
+		r.lastID++
+		next.ID = r.lastID
+		r.tasks[next.ID] = next
+		advance.Next, advance.Created = &next, true
+	}
+
+	r.tasks[oldHead.ID] = advance.Head
+	return advance, nil
+}
+
+func (r *MemoryTaskRepository) RemoveUser(ctx context.Context, userID int) ([]TaskChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var changes []TaskChange
+	for taskID, task := range r.tasks {
+		if unassigned, changed := unassignUser(task, userID); changed {
+			r.tasks[taskID] = unassigned
+			changes = append(changes, TaskChange{Previous: task, Task: unassigned})
+		}
+	}
+	return changes, nil
+}
+
+func (r *MemoryTaskRepository) TagCounts(ctx context.Context, query models.TaskQuery) ([]models.TagCount, error) {
+	query, err := normalizeTaskQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := map[string]int64{}
+	for _, task := range r.tasks {
+		if !matchesTaskQuery(task, query) {
+			continue
+		}
+		for _, tag := range task.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]models.TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, models.TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
+
+func (r *MemoryTaskRepository) DeleteTask(ctx context.Context, id string, actor models.Actor, version int) error {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, err := r.liveTask(taskID, actor)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(task, version); err != nil {
+		return err
+	}
+
+	deletedAt := time.Now().UTC().Truncate(time.Millisecond)
+	task.DeletedAt = &deletedAt
+	task.Version++
+	r.tasks[taskID] = task
+	return nil
+}
+
+func (r *MemoryTaskRepository) RestoreDeletedTask(ctx context.Context, id string, actor models.Actor, version int) (models.Task, error) {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, ok := r.tasks[taskID]
+	if !ok || !ownedBy(task, actor.OwnerScope()) || task.DeletedAt == nil {
+		return models.Task{}, &customError.NotFoundError{Resource: "Deleted task", ID: taskID}
+	}
+	if err := checkVersion(task, version); err != nil {
+		return models.Task{}, err
+	}
+
+	task.DeletedAt = nil
+	task.Version++
+	r.tasks[taskID] = task
+	return task, nil
+}
+
+func (r *MemoryTaskRepository) PurgeDeletedTasks(ctx context.Context, deletedBefore time.Time) (PurgeResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := []models.Task{}
+	for taskID, task := range r.tasks {
+		if task.DeletedAt != nil && task.DeletedAt.Before(deletedBefore) {
+			purged = append(purged, task)
+			delete(r.tasks, taskID)
+		}
+	}
+
+	result := PurgeResult{Purged: purged}
+	for taskID, task := range r.tasks {
+		if unlinked, changed := unlinkTask(task, purged); changed {
+			r.tasks[taskID] = unlinked
+			result.Unlinked = append(result.Unlinked, TaskChange{Previous: task, Task: unlinked})
+		}
+	}
+	return result, nil
+}
+
+func (r *MemoryTaskRepository) BulkWrite(ctx context.Context, actor models.Actor, ops []models.BulkOperation) ([]BulkResult, error) {
+	results, err := checkBulkOperations(ops)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deletedAt := time.Now().UTC().Truncate(time.Millisecond)
+	for i, op := range ops {
+		if results[i].Err != nil {
+			continue
+		}
+
+		var task models.Task
+		var err error
+		if op.Op == models.BulkCreate {
+			if task, err = bulkCreate(op); err == nil {
+				r.lastID++
+				task.ID = r.lastID
+			}
+		} else {
+			var oldTask models.Task
+			if oldTask, err = r.liveTask(op.ID, actor); err == nil {
+				results[i].Previous = &oldTask
+				if op.Op == models.BulkUpdate {
+					task, err = bulkUpdate(oldTask, op)
+				} else {
+					task, err = bulkDelete(oldTask, op, deletedAt)
+				}
+			}
+		}
+		if err == nil && op.Op != models.BulkDelete {
+			err = checkReferences(ctx, r, r.users, results[i].Previous, task)
+		}
+		if err != nil {
+			results[i] = BulkResult{Err: err}
+			continue
+		}
+
+		r.tasks[task.ID] = task
+		if op.Op != models.BulkDelete {
+			results[i].Task = &task
+		}
+	}
+	return results, nil
+}
+
+func (r *MemoryTaskRepository) PutTask(ctx context.Context, task models.Task) (PutResult, error) {
+	if err := validateFixture(task); err != nil {
+		return PutResult{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var existing *models.Task
+	if stored, ok := r.tasks[task.ID]; ok {
+		existing = &stored
+	}
+	changed, err := putTaskVersion(existing, &task)
+	if err != nil || !changed {
+		return PutResult{Previous: existing, Task: task}, err
+	}
+
+	r.tasks[task.ID] = task
+	r.lastID = max(r.lastID, task.ID)
+	return PutResult{Previous: existing, Task: task, Written: true}, nil
+}
+
+func (r *MemoryTaskRepository) AddATask(ctx context.Context, task models.Task) (models.Task, error) {
+	if err := validateNewTask(task); err != nil {
+		return models.Task{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := checkReferences(ctx, r, r.users, nil, task); err != nil {
+		return models.Task{}, err
+	}
+
+	r.lastID++
+	task.ID = r.lastID
+	task.Version = 1
+	task.Attachments = nil
+	task.SeriesID, task.Occurrence = 0, 0
+	task.DeletedAt = nil
+	r.tasks[task.ID] = task
+	return task, nil
+}
@@ -0,0 +1,135 @@
+package data
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"task_manager/customError"
+	"task_manager/models"
+)
+
+type MemoryUserRepository struct {
+	mu         sync.RWMutex
+	users      map[int]models.User
+	byUsername map[string]int
+	lastID     int
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: map[int]models.User{}, byUsername: map[string]int{}}
+}
+
+func (r *MemoryUserRepository) AddUser(ctx context.Context, user models.User) (models.User, error) {
+	user.Username = normalizeUsername(user.Username)
+	if err := validateUser(user); err != nil {
+		return models.User{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, taken := r.byUsername[user.Username]; taken {
+		return models.User{}, usernameTakenError(user.Username)
+	}
+
+	r.lastID++
+	user.ID = r.lastID
+	r.users[user.ID] = user
+	r.byUsername[user.Username] = user.ID
+	return user, nil
+}
+
+func (r *MemoryUserRepository) GetUser(ctx context.Context, id int) (models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, userNotFoundError(id)
+	}
+	return user, nil
+}
+
+func (r *MemoryUserRepository) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byUsername[normalizeUsername(username)]
+	if !ok {
+		return models.User{}, &customError.NotFoundError{Resource: "User"}
+	}
+	return r.users[id], nil
+}
+
+func (r *MemoryUserRepository) FindUsers(ctx context.Context, ids []int) (map[int]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	found := map[int]models.User{}
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok {
+			found[id] = user
+		}
+	}
+	return found, nil
+}
+
+func (r *MemoryUserRepository) ListUsers(ctx context.Context, offset, limit int) (models.UserPage, error) {
+	offset, limit, err := normalizePage(offset, limit)
+	if err != nil {
+		return models.UserPage{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]int, 0, len(r.users))
+	for id := range r.users {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	page := models.UserPage{Users: []models.User{}, Total: int64(len(ids)), Offset: offset, Limit: limit}
+	if offset < len(ids) {
+		for _, id := range ids[offset:min(offset+limit, len(ids))] {
+			page.Users = append(page.Users, r.users[id])
+		}
+	}
+	return page, nil
+}
+
+func (r *MemoryUserRepository) UpdateUser(ctx context.Context, user models.User) (models.User, error) {
+	user.Username = normalizeUsername(user.Username)
+	if err := validateUser(user); err != nil {
+		return models.User{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oldUser, ok := r.users[user.ID]
+	if !ok {
+		return models.User{}, userNotFoundError(user.ID)
+	}
+	if id, taken := r.byUsername[user.Username]; taken && id != user.ID {
+		return models.User{}, usernameTakenError(user.Username)
+	}
+
+	delete(r.byUsername, oldUser.Username)
+	r.users[user.ID] = user
+	r.byUsername[user.Username] = user.ID
+	return user, nil
+}
+
+func (r *MemoryUserRepository) DeleteUser(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return userNotFoundError(id)
+	}
+	delete(r.users, id)
+	delete(r.byUsername, user.Username)
+	return nil
+}
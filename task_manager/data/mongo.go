@@ -0,0 +1,42 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"task_manager/customError"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/topology"
+)
+
+// InitMongo connects to the server at connectionString and returns the
+// client together with the dbName database.
+func InitMongo(ctx context.Context, connectionString, dbName string) (*mongo.Client, *mongo.Database, error){
+	clientOptions := options.Client().ApplyURI(connectionString)
+
+	client, err := mongo.Connect(clientOptions)
+	if err != nil{
+		return nil, nil, fmt.Errorf("failed to configure MongoDB client: %w", err)
+	}
+
+	err = client.Ping(ctx, nil)
+	if err != nil{
+		_ = client.Disconnect(context.Background())
+		return nil, nil, wrapMongoError(err, "failed to reach MongoDB")
+	}
+
+	return client, client.Database(dbName), nil
+}
+
+// wrapMongoError adds what was being done to an error from the driver.
+// Errors meaning the server could not be reached become an UnavailableError
+// so they are reported as such instead of as internal errors.
+func wrapMongoError(err error, format string, args ...any) error {
+	wrapped := fmt.Errorf(format+": %w", append(args, err)...)
+	if mongo.IsNetworkError(err) || errors.As(err, &topology.ServerSelectionError{}) {
+		return &customError.UnavailableError{Reason: "database unreachable", Err: wrapped}
+	}
+	return wrapped
+}
@@ -0,0 +1,211 @@
+package data
+
+import (
+	"context"
+	"task_manager/customError"
+	"task_manager/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type MongoCommentRepository struct {
+	collection *mongo.Collection
+	counters   *mongo.Collection
+}
+
+const commentCounterID = "comments"
+
+func NewMongoCommentRepository(ctx context.Context, db *mongo.Database) (*MongoCommentRepository, error) {
+	repo := &MongoCommentRepository{
+		collection: db.Collection("comments"),
+		counters:   db.Collection("counters"),
+	}
+
+	err := createIndexes(ctx, repo.collection,
+		mongo.IndexModel{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		mongo.IndexModel{Keys: bson.D{{Key: "taskid", Value: 1}, {Key: "id", Value: 1}}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syncCounter(ctx, repo.counters, repo.collection, commentCounterID); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func commentFilter(taskID, commentID int) bson.D {
+	return bson.D{{Key: "id", Value: commentID}, {Key: "taskid", Value: taskID}}
+}
+
+func (r *MongoCommentRepository) AddComment(ctx context.Context, comment models.Comment) (models.Comment, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	body, err := normalizeCommentBody(comment.Body)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	comment = models.Comment{
+		TaskID:    comment.TaskID,
+		AuthorID:  comment.AuthorID,
+		Body:      body,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+		Version:   1,
+	}
+
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		commentID, err := nextSequence(ctx, r.counters, commentCounterID)
+		if err != nil {
+			return models.Comment{}, err
+		}
+
+		comment.ID = commentID
+		_, err = r.collection.InsertOne(ctx, comment)
+		if err == nil {
+			return comment, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return models.Comment{}, wrapMongoError(err, "failed to insert comment on task %d", comment.TaskID)
+		}
+
+		if err := syncCounter(ctx, r.counters, r.collection, commentCounterID); err != nil {
+			return models.Comment{}, err
+		}
+	}
+
+	return models.Comment{}, &customError.ConflictError{Reason: "Could not allocate a unique comment ID, please retry"}
+}
+
+// GetComments leaves the edits out on the server, as they can be much
+// larger than the comments themselves.
+func (r *MongoCommentRepository) GetComments(ctx context.Context, taskID, offset, limit int) (models.CommentPage, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	offset, limit, err := normalizePage(offset, limit)
+	if err != nil {
+		return models.CommentPage{}, err
+	}
+
+	filter := bson.D{{Key: "taskid", Value: taskID}}
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return models.CommentPage{}, wrapMongoError(err, "failed to count comments of task %d", taskID)
+	}
+
+	opts := options.Find().
+		SetProjection(bson.D{{Key: "edits", Value: 0}}).
+		SetSort(bson.D{{Key: "id", Value: 1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return models.CommentPage{}, wrapMongoError(err, "failed to fetch comments of task %d", taskID)
+	}
+	comments := []models.Comment{}
+	if err := cursor.All(ctx, &comments); err != nil {
+		return models.CommentPage{}, wrapMongoError(err, "failed to decode comments of task %d", taskID)
+	}
+
+	return models.CommentPage{Comments: comments, Total: total, Offset: offset, Limit: limit}, nil
+}
+
+func (r *MongoCommentRepository) GetComment(ctx context.Context, taskID, commentID int) (models.Comment, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	return r.findComment(ctx, taskID, commentID)
+}
+
+func (r *MongoCommentRepository) findComment(ctx context.Context, taskID, commentID int) (models.Comment, error) {
+	var comment models.Comment
+	err := r.collection.FindOne(ctx, commentFilter(taskID, commentID)).Decode(&comment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Comment{}, commentNotFoundError(commentID)
+		}
+		return models.Comment{}, wrapMongoError(err, "failed to fetch comment %d", commentID)
+	}
+	return comment, nil
+}
+
+func (r *MongoCommentRepository) UpdateComment(ctx context.Context, taskID, commentID, version int, body string) (models.Comment, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	body, err := normalizeCommentBody(body)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	oldComment, err := r.findComment(ctx, taskID, commentID)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if err := checkCommentVersion(oldComment, version); err != nil {
+		return models.Comment{}, err
+	}
+
+	comment, changed := editComment(oldComment, body, time.Now().UTC().Truncate(time.Millisecond))
+	if !changed {
+		return comment, nil
+	}
+
+	edit := comment.Edits[len(comment.Edits)-1]
+	filter := append(commentFilter(taskID, commentID), bson.E{Key: "version", Value: oldComment.Version})
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "body", Value: comment.Body},
+			{Key: "editedat", Value: comment.EditedAt},
+			{Key: "version", Value: comment.Version},
+		}},
+		{Key: "$push", Value: bson.D{{Key: "edits", Value: edit}}},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return models.Comment{}, wrapMongoError(err, "failed to update comment %d", commentID)
+	}
+	if result.MatchedCount == 0 {
+		return models.Comment{}, concurrentCommentWriteError(commentID, version)
+	}
+	return comment, nil
+}
+
+func (r *MongoCommentRepository) DeleteComment(ctx context.Context, taskID, commentID, version int) error {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	filter := commentFilter(taskID, commentID)
+	if version != AnyVersion {
+		filter = append(filter, bson.E{Key: "version", Value: version})
+	}
+
+	result, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return wrapMongoError(err, "failed to delete comment %d", commentID)
+	}
+	if result.DeletedCount == 0 {
+		// tell a missing comment apart from a stale If-Match
+		if _, err := r.findComment(ctx, taskID, commentID); err != nil {
+			return err
+		}
+		return staleCommentError(commentID)
+	}
+	return nil
+}
+
+func (r *MongoCommentRepository) DeleteTaskComments(ctx context.Context, taskIDs []int) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	result, err := r.collection.DeleteMany(ctx, bson.D{{Key: "taskid", Value: bson.D{{Key: "$in", Value: taskIDs}}}})
+	if err != nil {
+		return 0, wrapMongoError(err, "failed to delete comments of purged tasks")
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,66 @@
+package data
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// maxIDAttempts bounds how many fresh IDs an insert tries before giving up
+// when it keeps colliding on a unique id index.
+const maxIDAttempts = 3
+
+// syncCounter raises the named counter to at least the highest id stored in
+// coll, so documents inserted before the counter existed (or behind its
+// back) are never handed out again.
+func syncCounter(ctx context.Context, counters, coll *mongo.Collection, name string) error {
+	opts := options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})
+	var last struct {
+		ID int `bson:"id"`
+	}
+	err := coll.FindOne(ctx, bson.M{}, opts).Decode(&last)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return wrapMongoError(err, "failed to read highest %s id", name)
+	}
+
+	counterFilter := bson.D{{Key: "_id", Value: name}}
+	update := bson.D{{Key: "$max", Value: bson.D{{Key: "seq", Value: last.ID}}}}
+	_, err = counters.UpdateOne(ctx, counterFilter, update, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		return wrapMongoError(err, "failed to sync %s counter", name)
+	}
+	return nil
+}
+
+// nextSequence atomically increments the named counter and returns the new value.
+func nextSequence(ctx context.Context, counters *mongo.Collection, name string) (int, error) {
+	return reserveSequence(ctx, counters, name, 1)
+}
+
+// reserveSequence atomically advances the named counter by n and returns
+// the last of the n values reserved.
+func reserveSequence(ctx context.Context, counters *mongo.Collection, name string, n int) (int, error) {
+	counterFilter := bson.D{{Key: "_id", Value: name}}
+	update := bson.D{{Key: "$inc", Value: bson.D{{Key: "seq", Value: n}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter struct {
+		Seq int `bson:"seq"`
+	}
+	err := counters.FindOneAndUpdate(ctx, counterFilter, update, opts).Decode(&counter)
+	if err != nil {
+		return 0, wrapMongoError(err, "failed to allocate %s id", name)
+	}
+	return counter.Seq, nil
+}
+
+// createIndexes creates the given indexes on coll, wrapping any failure with
+// the collection name.
+func createIndexes(ctx context.Context, coll *mongo.Collection, indexes ...mongo.IndexModel) error {
+	if _, err := coll.Indexes().CreateMany(ctx, indexes); err != nil {
+		return wrapMongoError(err, "failed to create indexes on %s", coll.Name())
+	}
+	return nil
+}
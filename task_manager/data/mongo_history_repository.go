@@ -0,0 +1,53 @@
+package data
+
+import (
+	"context"
+	"task_manager/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type MongoHistoryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoHistoryRepository(ctx context.Context, db *mongo.Database) (*MongoHistoryRepository, error) {
+	repo := &MongoHistoryRepository{collection: db.Collection("task_history")}
+
+	err := createIndexes(ctx, repo.collection,
+		mongo.IndexModel{Keys: bson.D{{Key: "taskid", Value: 1}, {Key: "timestamp", Value: 1}}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *MongoHistoryRepository) AddEntry(ctx context.Context, entry models.TaskHistoryEntry) error {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	if _, err := r.collection.InsertOne(ctx, entry); err != nil {
+		return wrapMongoError(err, "failed to record history of task %d", entry.TaskID)
+	}
+	return nil
+}
+
+func (r *MongoHistoryRepository) GetTaskHistory(ctx context.Context, taskID int) ([]models.TaskHistoryEntry, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.D{{Key: "taskid", Value: taskID}}, opts)
+	if err != nil {
+		return nil, wrapMongoError(err, "failed to fetch history of task %d", taskID)
+	}
+
+	history := []models.TaskHistoryEntry{}
+	if err := cursor.All(ctx, &history); err != nil {
+		return nil, wrapMongoError(err, "failed to decode history of task %d", taskID)
+	}
+	return history, nil
+}
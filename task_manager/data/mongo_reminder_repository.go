@@ -0,0 +1,171 @@
+package data
+
+import (
+	"context"
+	"task_manager/customError"
+	"task_manager/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type MongoReminderRepository struct {
+	collection *mongo.Collection
+	counters   *mongo.Collection
+}
+
+const deliveryCounterID = "reminder_deliveries"
+
+func NewMongoReminderRepository(ctx context.Context, db *mongo.Database) (*MongoReminderRepository, error) {
+	repo := &MongoReminderRepository{
+		collection: db.Collection("reminder_deliveries"),
+		counters:   db.Collection("counters"),
+	}
+
+	err := createIndexes(ctx, repo.collection,
+		mongo.IndexModel{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// one delivery per reminder and channel, however many dispatchers
+		// find the reminder due
+		mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "taskid", Value: 1}, {Key: "offset", Value: 1},
+				{Key: "duedate", Value: 1}, {Key: "channel", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextattemptat", Value: 1}}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syncCounter(ctx, repo.counters, repo.collection, deliveryCounterID); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func reminderFilter(delivery models.ReminderDelivery) bson.D {
+	return bson.D{
+		{Key: "taskid", Value: delivery.TaskID}, {Key: "offset", Value: delivery.Offset},
+		{Key: "duedate", Value: delivery.DueDate}, {Key: "channel", Value: delivery.Channel},
+	}
+}
+
+func (r *MongoReminderRepository) AddDelivery(ctx context.Context, delivery models.ReminderDelivery) (models.ReminderDelivery, bool, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	delivery = newDelivery(delivery)
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		var stored models.ReminderDelivery
+		err := r.collection.FindOne(ctx, reminderFilter(delivery)).Decode(&stored)
+		if err == nil {
+			return stored, false, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return models.ReminderDelivery{}, false, wrapMongoError(err, "failed to look up reminder of task %d", delivery.TaskID)
+		}
+
+		if delivery.ID, err = nextSequence(ctx, r.counters, deliveryCounterID); err != nil {
+			return models.ReminderDelivery{}, false, err
+		}
+		_, err = r.collection.InsertOne(ctx, delivery)
+		if err == nil {
+			return delivery, true, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return models.ReminderDelivery{}, false, wrapMongoError(err, "failed to insert reminder of task %d", delivery.TaskID)
+		}
+
+		// either another dispatcher stored the reminder first, which the
+		// next lookup finds, or the counter fell behind the stored IDs
+		if err := syncCounter(ctx, r.counters, r.collection, deliveryCounterID); err != nil {
+			return models.ReminderDelivery{}, false, err
+		}
+	}
+
+	return models.ReminderDelivery{}, false, &customError.ConflictError{Reason: "Could not allocate a unique reminder delivery ID, please retry"}
+}
+
+// ClaimDeliveries claims one delivery at a time with FindOneAndUpdate, so
+// two dispatchers never get the same one.
+func (r *MongoReminderRepository) ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.ReminderDelivery, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "status", Value: models.DeliveryPending},
+		{Key: "nextattemptat", Value: bson.D{{Key: "$lte", Value: now}}},
+	}
+	update := bson.D{
+		{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}},
+		{Key: "$set", Value: bson.D{{Key: "nextattemptat", Value: leaseUntil}}},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextattemptat", Value: 1}, {Key: "id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var claimed []models.ReminderDelivery
+	for len(claimed) < limit {
+		var delivery models.ReminderDelivery
+		err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			return claimed, wrapMongoError(err, "failed to claim reminder deliveries")
+		}
+		claimed = append(claimed, delivery)
+	}
+	return claimed, nil
+}
+
+func (r *MongoReminderRepository) UpdateDelivery(ctx context.Context, delivery models.ReminderDelivery) error {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "status", Value: delivery.Status},
+		{Key: "nextattemptat", Value: delivery.NextAttemptAt},
+		{Key: "lasterror", Value: delivery.LastError},
+		{Key: "sentat", Value: delivery.SentAt},
+	}}}
+	result, err := r.collection.UpdateOne(ctx, bson.D{{Key: "id", Value: delivery.ID}}, update)
+	if err != nil {
+		return wrapMongoError(err, "failed to update reminder delivery %d", delivery.ID)
+	}
+	if result.MatchedCount == 0 {
+		return deliveryNotFoundError(delivery.ID)
+	}
+	return nil
+}
+
+func (r *MongoReminderRepository) GetTaskDeliveries(ctx context.Context, taskID int) ([]models.ReminderDelivery, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.D{{Key: "taskid", Value: taskID}}, opts)
+	if err != nil {
+		return nil, wrapMongoError(err, "failed to fetch reminders of task %d", taskID)
+	}
+	deliveries := []models.ReminderDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, wrapMongoError(err, "failed to decode reminders of task %d", taskID)
+	}
+	return deliveries, nil
+}
+
+func (r *MongoReminderRepository) DeleteTaskDeliveries(ctx context.Context, taskIDs []int) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	result, err := r.collection.DeleteMany(ctx, bson.D{{Key: "taskid", Value: bson.D{{Key: "$in", Value: taskIDs}}}})
+	if err != nil {
+		return 0, wrapMongoError(err, "failed to delete reminders of tasks %v", taskIDs)
+	}
+	return result.DeletedCount, nil
+}
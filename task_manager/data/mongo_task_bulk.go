@@ -0,0 +1,194 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"task_manager/customError"
+	"task_manager/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// BulkWrite checks every operation against the tasks as they were before
+// the batch, then sends all writes in one unordered BulkWrite. Updates and
+// deletes are conditioned on the version that was checked, like their
+// single-task counterparts.
+func (r *MongoTaskRepository) BulkWrite(ctx context.Context, actor models.Actor, ops []models.BulkOperation) ([]BulkResult, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	results, err := checkBulkOperations(ops)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := r.findTasks(ctx, bulkTaskIDs(ops, results), actor)
+	if err != nil {
+		return nil, err
+	}
+
+	deletedAt := time.Now().UTC().Truncate(time.Millisecond)
+	var creates []int
+	for i, op := range ops {
+		if results[i].Err != nil {
+			continue
+		}
+
+		var task models.Task
+		var err error
+		if op.Op == models.BulkCreate {
+			if task, err = bulkCreate(op); err == nil {
+				creates = append(creates, i)
+			}
+		} else if oldTask, ok := stored[op.ID]; !ok {
+			err = &customError.NotFoundError{ID: op.ID}
+		} else {
+			results[i].Previous = &oldTask
+			if op.Op == models.BulkUpdate {
+				task, err = bulkUpdate(oldTask, op)
+			} else {
+				task, err = bulkDelete(oldTask, op, deletedAt)
+			}
+		}
+		if err == nil && op.Op != models.BulkDelete {
+			err = checkReferences(ctx, r, r.users, results[i].Previous, task)
+		}
+		if err != nil {
+			results[i] = BulkResult{Err: err}
+			continue
+		}
+		results[i].Task = &task
+	}
+
+	// one counter update reserves the IDs of every create
+	if len(creates) > 0 {
+		lastID, err := reserveSequence(ctx, r.counters, taskCounterID, len(creates))
+		if err != nil {
+			return nil, err
+		}
+		for n, i := range creates {
+			results[i].Task.ID = lastID - len(creates) + 1 + n
+		}
+	}
+
+	var writes []mongo.WriteModel
+	var writeOps []int // index into ops of each write
+	for i, op := range ops {
+		if results[i].Err != nil {
+			continue
+		}
+		task := *results[i].Task
+		switch op.Op {
+		case models.BulkCreate:
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(task))
+		case models.BulkUpdate:
+			writes = append(writes, mongo.NewReplaceOneModel().SetFilter(versionedTaskFilter(*results[i].Previous)).SetReplacement(task))
+		case models.BulkDelete:
+			update := bson.D{{Key: "$set", Value: bson.D{{Key: "deletedat", Value: deletedAt}, {Key: "version", Value: task.Version}}}}
+			writes = append(writes, mongo.NewUpdateOneModel().SetFilter(versionedTaskFilter(*results[i].Previous)).SetUpdate(update))
+			results[i].Task = nil
+		}
+		writeOps = append(writeOps, i)
+	}
+	if len(writes) == 0 {
+		return results, nil
+	}
+
+	result, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	var writeErr mongo.BulkWriteException
+	if errors.As(err, &writeErr) && writeErr.WriteConcernError == nil {
+		for _, failed := range writeErr.WriteErrors {
+			i := writeOps[failed.Index]
+			results[i] = BulkResult{Err: wrapMongoError(failed.WriteError, "failed to %s task", ops[i].Op)}
+			if ops[i].Op == models.BulkCreate && mongo.IsDuplicateKeyError(failed.WriteError) {
+				results[i].Err = &customError.ConflictError{Reason: "Could not allocate a unique task ID, please retry"}
+			}
+		}
+		// a duplicate key means the counter fell behind; catch it up
+		if err := syncCounter(ctx, r.counters, r.collection, taskCounterID); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, wrapMongoError(err, "failed to run bulk write")
+	}
+
+	if conditional := len(bulkTaskIDs(ops, results)); result == nil || result.MatchedCount < int64(conditional) {
+		if err := r.checkBulkRaces(ctx, ops, results, deletedAt); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// bulkTaskIDs lists the tasks the valid updates and deletes refer to.
+func bulkTaskIDs(ops []models.BulkOperation, results []BulkResult) []int {
+	var ids []int
+	for i, op := range ops {
+		if op.Op != models.BulkCreate && results[i].Err == nil {
+			ids = append(ids, op.ID)
+		}
+	}
+	return ids
+}
+
+// findTasks loads the live tasks with the given IDs that actor may see.
+func (r *MongoTaskRepository) findTasks(ctx context.Context, ids []int, actor models.Actor) (map[int]models.Task, error) {
+	tasks := map[int]models.Task{}
+	if len(ids) == 0 {
+		return tasks, nil
+	}
+
+	filter := taskQueryFilter(models.TaskQuery{OwnerID: actor.OwnerScope()})
+	filter = append(filter, bson.E{Key: "id", Value: bson.D{{Key: "$in", Value: ids}}})
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, wrapMongoError(err, "failed to fetch tasks")
+	}
+	var found []models.Task
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, wrapMongoError(err, "failed to decode tasks")
+	}
+	for _, task := range found {
+		tasks[task.ID] = task
+	}
+	return tasks, nil
+}
+
+// checkBulkRaces finds the updates and deletes whose version condition did
+// not match because the task changed after it was checked. BulkWrite only
+// reports how many matched, so the tasks are read back to tell which.
+func (r *MongoTaskRepository) checkBulkRaces(ctx context.Context, ops []models.BulkOperation, results []BulkResult, deletedAt time.Time) error {
+	ids := bulkTaskIDs(ops, results)
+	cursor, err := r.collection.Find(ctx, bson.D{{Key: "id", Value: bson.D{{Key: "$in", Value: ids}}}})
+	if err != nil {
+		return wrapMongoError(err, "failed to verify bulk write")
+	}
+	var found []models.Task
+	if err := cursor.All(ctx, &found); err != nil {
+		return wrapMongoError(err, "failed to verify bulk write")
+	}
+	current := map[int]models.Task{}
+	for _, task := range found {
+		current[task.ID] = task
+	}
+
+	for i, op := range ops {
+		if op.Op == models.BulkCreate || results[i].Err != nil {
+			continue
+		}
+		task := current[op.ID]
+		applied := false
+		if op.Op == models.BulkUpdate {
+			applied = task.Version == results[i].Task.Version && task.DeletedAt == nil
+		} else {
+			applied = task.Version == results[i].Previous.Version && task.DeletedAt != nil && task.DeletedAt.Equal(deletedAt)
+		}
+		if !applied {
+			results[i] = BulkResult{Err: concurrentWriteError(op.ID, op.Version)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,1175 @@
+package data
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"slices"
+	"task_manager/customError"
+	"task_manager/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type MongoTaskRepository struct {
+	collection *mongo.Collection
+	counters   *mongo.Collection
+	users      UserRepository
+}
+
+// taskCounterID is the _id of the document in the counters collection that
+// holds the last allocated task ID.
+const taskCounterID = "tasks"
+
+// NewMongoTaskRepository stores tasks in the named collection, which gets
+// the sample task if seed is set and it is empty. users is used to check
+// the assignees and watchers of tasks.
+func NewMongoTaskRepository(ctx context.Context, db *mongo.Database, collection string, seed bool, users UserRepository) (*MongoTaskRepository, error) {
+	repo := &MongoTaskRepository{
+		collection: db.Collection(collection),
+		counters:   db.Collection("counters"),
+		users:      users,
+	}
+
+	err := createIndexes(ctx, repo.collection,
+		mongo.IndexModel{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		mongo.IndexModel{Keys: bson.D{{Key: "ownerid", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "duedate", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "deletedat", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "tags", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "parentid", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "blockedby", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "assignees", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "watchers", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "recurrence", Value: 1}}},
+		// numbers the occurrences of a series once, however many
+		// schedulers try to create them
+		mongo.IndexModel{
+			Keys: bson.D{{Key: "seriesid", Value: 1}, {Key: "occurrence", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.D{{Key: "seriesid", Value: bson.D{{Key: "$gt", Value: 0}}}}),
+		},
+		mongo.IndexModel{
+			Keys: bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().SetName("task_text").SetWeights(bson.D{
+				{Key: "title", Value: searchWeights["title"]},
+				{Key: "description", Value: searchWeights["description"]},
+			}),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if seed {
+		count, err := repo.collection.CountDocuments(ctx, bson.D{{}})
+		if err != nil {
+			return nil, wrapMongoError(err, "failed to count tasks")
+		}
+
+		if count == 0 {
+			_, err = repo.collection.InsertOne(ctx, seedTask())
+			if err != nil {
+				return nil, wrapMongoError(err, "failed to insert seed task")
+			}
+		}
+	}
+
+	if err := syncCounter(ctx, repo.counters, repo.collection, taskCounterID); err != nil {
+		return nil, err
+	}
+
+	if err := repo.migrateStringDueDates(ctx); err != nil {
+		return nil, err
+	}
+
+	// tasks stored before versioning was introduced start at version 1
+	unversioned := bson.D{{Key: "version", Value: bson.D{{Key: "$exists", Value: false}}}}
+	_, err = repo.collection.UpdateMany(ctx, unversioned, bson.D{{Key: "$set", Value: bson.D{{Key: "version", Value: 1}}}})
+	if err != nil {
+		return nil, wrapMongoError(err, "failed to version existing tasks")
+	}
+
+	return repo, nil
+}
+
+// migrateStringDueDates converts due dates stored as free-form strings,
+// from before due dates were typed, into BSON dates. Values that can not be
+// parsed are left untouched and logged so they can be fixed by hand.
+func (r *MongoTaskRepository) migrateStringDueDates(ctx context.Context) error {
+	stringDates := bson.D{{Key: "duedate", Value: bson.D{{Key: "$type", Value: "string"}}}}
+	cursor, err := r.collection.Find(ctx, stringDates)
+	if err != nil {
+		return wrapMongoError(err, "failed to look up string due dates")
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var legacy struct {
+			ID      int    `bson:"id"`
+			DueDate string `bson:"duedate"`
+		}
+		if err := cursor.Decode(&legacy); err != nil {
+			return wrapMongoError(err, "failed to decode legacy task")
+		}
+
+		dueDate, _, err := models.ParseDueDate(legacy.DueDate)
+		if err != nil {
+			log.Printf("Task %d keeps its unparseable due date: %v", legacy.ID, err)
+			continue
+		}
+
+		_, err = r.collection.UpdateOne(ctx,
+			bson.D{{Key: "id", Value: legacy.ID}, {Key: "duedate", Value: legacy.DueDate}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "duedate", Value: dueDate}}}})
+		if err != nil {
+			return wrapMongoError(err, "failed to migrate due date of task %d", legacy.ID)
+		}
+	}
+	return cursor.Err()
+}
+
+// notDeleted matches tasks outside the trash; a null deletedat also matches
+// tasks stored before soft deletes existed, which lack the field.
+var notDeleted = bson.E{Key: "deletedat", Value: nil}
+
+// taskFilter matches a single live task, restricted to ownerID unless it is
+// AllOwners.
+func taskFilter(taskID, ownerID int) bson.D {
+	filter := bson.D{{Key: "id", Value: taskID}, notDeleted}
+	if ownerID != AllOwners {
+		filter = append(filter, bson.E{Key: "ownerid", Value: ownerID})
+	}
+	return filter
+}
+
+// readableTaskFilter matches the live task with taskID if actor may open
+// it, as models.Actor.CanRead decides.
+func readableTaskFilter(taskID int, actor models.Actor) bson.D {
+	filter := bson.D{{Key: "id", Value: taskID}, notDeleted}
+	if !actor.Admin {
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "ownerid", Value: actor.UserID}},
+			bson.D{{Key: "assignees", Value: actor.UserID}},
+			bson.D{{Key: "watchers", Value: actor.UserID}},
+		}})
+	}
+	return filter
+}
+
+// trashedTaskFilter is taskFilter for tasks in the trash.
+func trashedTaskFilter(taskID, ownerID int) bson.D {
+	filter := bson.D{{Key: "id", Value: taskID}, {Key: "deletedat", Value: bson.D{{Key: "$ne", Value: nil}}}}
+	if ownerID != AllOwners {
+		filter = append(filter, bson.E{Key: "ownerid", Value: ownerID})
+	}
+	return filter
+}
+
+func (r *MongoTaskRepository) GetAllTasks(ctx context.Context, query models.TaskQuery) (models.TaskPage, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	query, err := normalizeTaskQuery(query)
+	if err != nil {
+		return models.TaskPage{}, err
+	}
+
+	filter := taskQueryFilter(query)
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return models.TaskPage{}, wrapMongoError(err, "failed to count tasks")
+	}
+
+	opts := options.Find().
+		SetSort(taskSort(query)).
+		SetSkip(int64(query.Offset)).
+		SetLimit(int64(query.Limit))
+
+	allTasks := []*models.Task{}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return models.TaskPage{}, wrapMongoError(err, "failed to fetch tasks")
+	}
+
+	defer func() {
+		if err := cursor.Close(ctx); err != nil {
+			log.Printf("Error closing cursor: %v", err)
+		}
+	}()
+
+	for cursor.Next(ctx) {
+		var task models.Task
+		if err := cursor.Decode(&task); err != nil {
+			log.Printf("Error decoding task: %v", err)
+			continue // Skip problematic documents but continue processing others
+		}
+		allTasks = append(allTasks, &task)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return models.TaskPage{}, wrapMongoError(err, "cursor error")
+	}
+
+	return models.TaskPage{
+		Tasks:  allTasks,
+		Total:  total,
+		Offset: query.Offset,
+		Limit:  query.Limit,
+	}, nil
+}
+
+// taskSort orders tasks as requested by a normalized TaskQuery. id is
+// unique, so using it as a tie-breaker keeps pages stable.
+func taskSort(query models.TaskQuery) bson.D {
+	direction := 1
+	if query.SortDesc {
+		direction = -1
+	}
+	sort := bson.D{{Key: sortableTaskFields[query.SortBy], Value: direction}}
+	if query.SortBy != "id" {
+		sort = append(sort, bson.E{Key: "id", Value: direction})
+	}
+	return sort
+}
+
+// EachTask walks a cursor instead of loading every task. The read timeout
+// bounds each round trip to the server rather than the whole walk, which
+// may take as long as fn does.
+func (r *MongoTaskRepository) EachTask(ctx context.Context, query models.TaskQuery, fn func(models.Task) error) error {
+	query, err := normalizeTaskQuery(query)
+	if err != nil {
+		return err
+	}
+
+	findCtx, cancel := readContext(ctx)
+	cursor, err := r.collection.Find(findCtx, taskQueryFilter(query), options.Find().SetSort(taskSort(query)))
+	cancel()
+	if err != nil {
+		return wrapMongoError(err, "failed to fetch tasks")
+	}
+
+	defer func() {
+		if err := cursor.Close(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("Error closing cursor: %v", err)
+		}
+	}()
+
+	for {
+		nextCtx, cancel := readContext(ctx)
+		more := cursor.Next(nextCtx)
+		cancel()
+		if !more {
+			break
+		}
+
+		var task models.Task
+		if err := cursor.Decode(&task); err != nil {
+			log.Printf("Error decoding task: %v", err)
+			continue
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return wrapMongoError(err, "cursor error")
+	}
+	return nil
+}
+
+// SearchTasks relies on the text index created at startup. Highlights are
+// computed here rather than by the server, which has no such feature.
+func (r *MongoTaskRepository) SearchTasks(ctx context.Context, text string, query models.TaskQuery) (models.TaskSearchPage, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	search, err := parseSearch(text)
+	if err != nil {
+		return models.TaskSearchPage{}, err
+	}
+	query, err = normalizeTaskQuery(query)
+	if err != nil {
+		return models.TaskSearchPage{}, err
+	}
+
+	filter := append(taskQueryFilter(query), bson.E{Key: "$text", Value: bson.D{{Key: "$search", Value: text}}})
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return models.TaskSearchPage{}, wrapMongoError(err, "failed to count search hits")
+	}
+
+	score := bson.D{{Key: "$meta", Value: "textScore"}}
+	opts := options.Find().
+		SetProjection(bson.D{{Key: "score", Value: score}}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "id", Value: 1}}).
+		SetSkip(int64(query.Offset)).
+		SetLimit(int64(query.Limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return models.TaskSearchPage{}, wrapMongoError(err, "failed to search tasks")
+	}
+
+	defer func() {
+		if err := cursor.Close(ctx); err != nil {
+			log.Printf("Error closing cursor: %v", err)
+		}
+	}()
+
+	hits := []models.TaskSearchHit{}
+	for cursor.Next(ctx) {
+		var task models.Task
+		if err := cursor.Decode(&task); err != nil {
+			log.Printf("Error decoding task: %v", err)
+			continue
+		}
+		score, _ := cursor.Current.Lookup("score").DoubleOK()
+		hits = append(hits, models.TaskSearchHit{Task: &task, Score: score, Highlights: search.highlightTask(task)})
+	}
+
+	if err := cursor.Err(); err != nil {
+		return models.TaskSearchPage{}, wrapMongoError(err, "cursor error")
+	}
+
+	return models.TaskSearchPage{
+		Hits:   hits,
+		Total:  total,
+		Offset: query.Offset,
+		Limit:  query.Limit,
+	}, nil
+}
+
+// taskQueryFilter translates a normalized TaskQuery into a Mongo filter.
+func taskQueryFilter(query models.TaskQuery) bson.D {
+	filter := bson.D{notDeleted}
+	if query.Trash {
+		filter = bson.D{{Key: "deletedat", Value: bson.D{{Key: "$ne", Value: nil}}}}
+	}
+
+	if query.OwnerID != AllOwners {
+		filter = append(filter, bson.E{Key: "ownerid", Value: query.OwnerID})
+	}
+
+	if query.Status != "" {
+		filter = append(filter, bson.E{Key: "status", Value: query.Status})
+	}
+
+	dueRange := bson.D{}
+	if !query.DueAfter.IsZero() {
+		dueRange = append(dueRange, bson.E{Key: "$gte", Value: query.DueAfter})
+	}
+	if !query.DueBefore.IsZero() {
+		dueRange = append(dueRange, bson.E{Key: "$lte", Value: query.DueBefore})
+	}
+	if query.Overdue {
+		dueRange = append(dueRange, bson.E{Key: "$lt", Value: time.Now()})
+		// wrapped in $and so it can coexist with an explicit status filter
+		openOnly := bson.D{{Key: "status", Value: bson.D{{Key: "$nin", Value: models.ClosedStatuses}}}}
+		filter = append(filter, bson.E{Key: "$and", Value: bson.A{openOnly}})
+	}
+	if len(dueRange) > 0 {
+		filter = append(filter, bson.E{Key: "duedate", Value: dueRange})
+	}
+
+	if query.Title != "" {
+		filter = append(filter, bson.E{Key: "title", Value: bson.D{
+			{Key: "$regex", Value: regexp.QuoteMeta(query.Title)},
+			{Key: "$options", Value: "i"},
+		}})
+	}
+
+	if query.ParentID != 0 {
+		filter = append(filter, bson.E{Key: "parentid", Value: query.ParentID})
+	}
+	if query.AssigneeID != 0 {
+		filter = append(filter, bson.E{Key: "assignees", Value: query.AssigneeID})
+	}
+	if query.WatcherID != 0 {
+		filter = append(filter, bson.E{Key: "watchers", Value: query.WatcherID})
+	}
+
+	if query.Recurring {
+		filter = append(filter, bson.E{Key: "recurrence", Value: bson.D{{Key: "$gt", Value: ""}}})
+	}
+	if query.HasReminders {
+		filter = append(filter, bson.E{Key: "reminders.0", Value: bson.D{{Key: "$exists", Value: true}}})
+	}
+
+	tags := bson.D{}
+	if len(query.AllTags) > 0 {
+		tags = append(tags, bson.E{Key: "$all", Value: query.AllTags})
+	}
+	if len(query.AnyTags) > 0 {
+		tags = append(tags, bson.E{Key: "$in", Value: query.AnyTags})
+	}
+	if len(tags) > 0 {
+		filter = append(filter, bson.E{Key: "tags", Value: tags})
+	}
+
+	return filter
+}
+
+func (r *MongoTaskRepository) GetTask(ctx context.Context, id string, actor models.Actor) (models.Task, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	var task models.Task
+	err = r.collection.FindOne(ctx, readableTaskFilter(taskID, actor)).Decode(&task)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Task{}, &customError.NotFoundError{ID: taskID}
+		}
+		return models.Task{}, wrapMongoError(err, "failed to fetch task %d", taskID)
+	}
+	return task, nil
+}
+
+func (r *MongoTaskRepository) UpdateTask(ctx context.Context, id string, actor models.Actor, version int, updatedTask models.Task) (models.Task, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if err := validateTask(updatedTask); err != nil {
+		return models.Task{}, err
+	}
+
+	oldTask, err := r.findTask(ctx, taskID, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return models.Task{}, err
+	}
+	if err := checkTransition(oldTask, updatedTask); err != nil {
+		return models.Task{}, err
+	}
+
+	updatedTask.ID = oldTask.ID
+	updatedTask.OwnerID = oldTask.OwnerID
+	updatedTask.Attachments = oldTask.Attachments
+	updatedTask.SeriesID, updatedTask.Occurrence = oldTask.SeriesID, oldTask.Occurrence
+	updatedTask.Version = oldTask.Version + 1
+	updatedTask.DeletedAt = nil
+	if err := checkReferences(ctx, r, r.users, &oldTask, updatedTask); err != nil {
+		return models.Task{}, err
+	}
+
+	// matching on the version we read makes the read-modify-write atomic
+	result, err := r.collection.ReplaceOne(ctx, versionedTaskFilter(oldTask), updatedTask)
+	if err != nil {
+		return models.Task{}, wrapMongoError(err, "failed to update task %d", taskID)
+	}
+	if result.MatchedCount == 0 {
+		return models.Task{}, concurrentWriteError(taskID, version)
+	}
+
+	return updatedTask, nil
+}
+
+func (r *MongoTaskRepository) PatchTask(ctx context.Context, id string, actor models.Actor, version int, format PatchFormat, patch []byte) (models.Task, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	oldTask, err := r.findTask(ctx, taskID, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return models.Task{}, err
+	}
+
+	patchedTask, err := applyTaskPatch(oldTask, format, patch)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := validateTaskUpdate(oldTask, patchedTask); err != nil {
+		return models.Task{}, err
+	}
+	if err := checkReferences(ctx, r, r.users, &oldTask, patchedTask); err != nil {
+		return models.Task{}, err
+	}
+
+	changed, err := changedTaskFields(oldTask, patchedTask)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if len(changed) == 0 {
+		return patchedTask, nil
+	}
+
+	patchedTask.Version = oldTask.Version + 1
+	changed = append(changed, bson.E{Key: "version", Value: patchedTask.Version})
+
+	result, err := r.collection.UpdateOne(ctx, versionedTaskFilter(oldTask), bson.D{{Key: "$set", Value: changed}})
+	if err != nil {
+		return models.Task{}, wrapMongoError(err, "failed to patch task %d", taskID)
+	}
+	if result.MatchedCount == 0 {
+		return models.Task{}, concurrentWriteError(taskID, version)
+	}
+
+	return patchedTask, nil
+}
+
+func (r *MongoTaskRepository) UpdateTags(ctx context.Context, id string, actor models.Actor, version int, add, remove []string) (models.Task, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	oldTask, err := r.findTask(ctx, taskID, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return models.Task{}, err
+	}
+
+	task, changed := retagTask(oldTask, add, remove)
+	if !changed {
+		return task, nil
+	}
+	if err := validateTask(task); err != nil {
+		return models.Task{}, err
+	}
+
+	task.Version++
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "tags", Value: task.Tags}, {Key: "version", Value: task.Version}}}}
+	result, err := r.collection.UpdateOne(ctx, versionedTaskFilter(oldTask), update)
+	if err != nil {
+		return models.Task{}, wrapMongoError(err, "failed to update tags of task %d", taskID)
+	}
+	if result.MatchedCount == 0 {
+		return models.Task{}, concurrentWriteError(taskID, version)
+	}
+
+	return task, nil
+}
+
+func (r *MongoTaskRepository) UpdateTaskUsers(ctx context.Context, id string, actor models.Actor, version int, list models.UserList, add, remove []int) (models.Task, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	oldTask, err := r.findTask(ctx, taskID, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return models.Task{}, err
+	}
+
+	task, changed := updateTaskUsers(oldTask, list, add, remove)
+	if !changed {
+		return task, nil
+	}
+	if err := validateTask(task); err != nil {
+		return models.Task{}, err
+	}
+	if err := checkTaskUsers(ctx, r.users, &oldTask, task); err != nil {
+		return models.Task{}, err
+	}
+
+	task.Version++
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: string(list), Value: *task.Users(list)}, {Key: "version", Value: task.Version}}}}
+	result, err := r.collection.UpdateOne(ctx, versionedTaskFilter(oldTask), update)
+	if err != nil {
+		return models.Task{}, wrapMongoError(err, "failed to update %s of task %d", list, taskID)
+	}
+	if result.MatchedCount == 0 {
+		return models.Task{}, concurrentWriteError(taskID, version)
+	}
+
+	return task, nil
+}
+
+func (r *MongoTaskRepository) AddAttachment(ctx context.Context, id string, actor models.Actor, version int, attachment models.Attachment) (models.Task, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	oldTask, err := r.findTask(ctx, taskID, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return models.Task{}, err
+	}
+	if err := validateAttachment(attachment); err != nil {
+		return models.Task{}, err
+	}
+
+	// attachments live inside their task, so the counter is never synced
+	// with a collection
+	if attachment.ID, err = nextSequence(ctx, r.counters, attachmentCounterID); err != nil {
+		return models.Task{}, err
+	}
+	task, err := addAttachment(oldTask, attachment)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if err := r.setAttachments(ctx, oldTask, task, version); err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
+}
+
+func (r *MongoTaskRepository) RemoveAttachment(ctx context.Context, id string, actor models.Actor, version int, attachmentID int) (models.Task, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	oldTask, err := r.findTask(ctx, taskID, actor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkVersion(oldTask, version); err != nil {
+		return models.Task{}, err
+	}
+
+	task, err := removeAttachment(oldTask, attachmentID)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if err := r.setAttachments(ctx, oldTask, task, version); err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
+}
+
+// setAttachments writes the attachments and version of task over oldTask.
+func (r *MongoTaskRepository) setAttachments(ctx context.Context, oldTask, task models.Task, version int) error {
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "attachments", Value: task.Attachments}, {Key: "version", Value: task.Version}}}}
+	result, err := r.collection.UpdateOne(ctx, versionedTaskFilter(oldTask), update)
+	if err != nil {
+		return wrapMongoError(err, "failed to update attachments of task %d", task.ID)
+	}
+	if result.MatchedCount == 0 {
+		return concurrentWriteError(task.ID, version)
+	}
+	return nil
+}
+
+// AdvanceSeries inserts the next occurrence before moving the rule off the
+// head; the unique index on series and occurrence keeps a repeated or
+// concurrent call from storing it twice.
+func (r *MongoTaskRepository) AdvanceSeries(ctx context.Context, head models.Task) (SeriesAdvance, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	oldHead, err := r.findTask(ctx, head.ID, models.Actor{Admin: true})
+	if err != nil {
+		return SeriesAdvance{}, err
+	}
+	if err := checkVersion(oldHead, head.Version); err != nil {
+		return SeriesAdvance{}, err
+	}
+	due, ok := NextOccurrence(oldHead)
+	if !ok {
+		return SeriesAdvance{Previous: oldHead, Head: oldHead}, nil
+	}
+
+	next, created, err := r.insertOccurrence(ctx, newOccurrence(oldHead, due))
+	if err != nil {
+		return SeriesAdvance{}, err
+	}
+
+	// if this fails, the next call finds the occurrence stored above
+	newHead := moveRuleOff(oldHead)
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "recurrence", Value: newHead.Recurrence}, {Key: "version", Value: newHead.Version}}}}
+	result, err := r.collection.UpdateOne(ctx, versionedTaskFilter(oldHead), update)
+	if err != nil {
+		return SeriesAdvance{}, wrapMongoError(err, "failed to update recurrence of task %d", oldHead.ID)
+	}
+	if result.MatchedCount == 0 {
+		return SeriesAdvance{}, concurrentWriteError(oldHead.ID, head.Version)
+	}
+
+	return SeriesAdvance{Previous: oldHead, Head: newHead, Next: &next, Created: created}, nil
+}
+
+// insertOccurrence stores a new occurrence of a series and reports true,
+// or returns the occurrence already stored under its number and false.
+func (r *MongoTaskRepository) insertOccurrence(ctx context.Context, task models.Task) (models.Task, bool, error) {
+	filter := bson.D{{Key: "seriesid", Value: task.SeriesID}, {Key: "occurrence", Value: task.Occurrence}}
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		var stored models.Task
+		err := r.collection.FindOne(ctx, filter).Decode(&stored)
+		if err == nil {
+			return stored, false, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return models.Task{}, false, wrapMongoError(err, "failed to look up occurrence %d of series %d", task.Occurrence, task.SeriesID)
+		}
+
+		if task.ID, err = nextSequence(ctx, r.counters, taskCounterID); err != nil {
+			return models.Task{}, false, err
+		}
+		_, err = r.collection.InsertOne(ctx, task)
+		if err == nil {
+			return task, true, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return models.Task{}, false, wrapMongoError(err, "failed to insert task %d", task.ID)
+		}
+
+		// either the occurrence was created concurrently, which the next
+		// lookup finds, or the counter fell behind the stored IDs
+		if err := syncCounter(ctx, r.counters, r.collection, taskCounterID); err != nil {
+			return models.Task{}, false, err
+		}
+	}
+
+	return models.Task{}, false, &customError.ConflictError{Reason: "Could not allocate a unique task ID, please retry"}
+}
+
+// RemoveUser changes each affected task with its own conditional write,
+// including the tasks in the trash, so every change can be recorded.
+func (r *MongoTaskRepository) RemoveUser(ctx context.Context, userID int) ([]TaskChange, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	onAnyList := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "assignees", Value: userID}},
+		bson.D{{Key: "watchers", Value: userID}},
+	}}}
+	changes, err := r.updateEach(ctx, onAnyList, func(task models.Task) (models.Task, bool) {
+		return unassignUser(task, userID)
+	})
+	if err != nil {
+		return changes, wrapMongoError(err, "failed to remove user %d from tasks", userID)
+	}
+	return changes, nil
+}
+
+// TagCounts counts with an aggregation, so only the counts leave the
+// server.
+func (r *MongoTaskRepository) TagCounts(ctx context.Context, query models.TaskQuery) ([]models.TagCount, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	query, err := normalizeTaskQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: taskQueryFilter(query)}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$tags"}, {Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, wrapMongoError(err, "failed to count tags")
+	}
+
+	var counts []struct {
+		Tag   string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, wrapMongoError(err, "failed to read tag counts")
+	}
+
+	tags := make([]models.TagCount, len(counts))
+	for i, count := range counts {
+		tags[i] = models.TagCount{Tag: count.Tag, Count: count.Count}
+	}
+	return tags, nil
+}
+
+func (r *MongoTaskRepository) DeleteTask(ctx context.Context, id string, actor models.Actor, version int) error {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return err
+	}
+
+	filter := taskFilter(taskID, actor.OwnerScope())
+	if version != AnyVersion {
+		filter = append(filter, bson.E{Key: "version", Value: version})
+	}
+
+	deletedAt := time.Now().UTC().Truncate(time.Millisecond)
+	update := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "deletedat", Value: deletedAt}}},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return wrapMongoError(err, "failed to delete task %d", taskID)
+	}
+
+	if result.MatchedCount == 0 {
+		// tell a missing task apart from a stale If-Match
+		if _, err := r.findTask(ctx, taskID, actor); err != nil {
+			return err
+		}
+		return staleVersionError(taskID)
+	}
+
+	return nil
+}
+
+func (r *MongoTaskRepository) RestoreDeletedTask(ctx context.Context, id string, actor models.Actor, version int) (models.Task, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	filter := trashedTaskFilter(taskID, actor.OwnerScope())
+	var task models.Task
+	err = r.collection.FindOne(ctx, filter).Decode(&task)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Task{}, &customError.NotFoundError{Resource: "Deleted task", ID: taskID}
+		}
+		return models.Task{}, wrapMongoError(err, "failed to fetch deleted task %d", taskID)
+	}
+	if err := checkVersion(task, version); err != nil {
+		return models.Task{}, err
+	}
+
+	filter = append(filter, bson.E{Key: "version", Value: task.Version})
+	update := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "deletedat", Value: nil}}},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return models.Task{}, wrapMongoError(err, "failed to restore task %d", taskID)
+	}
+	if result.MatchedCount == 0 {
+		return models.Task{}, concurrentWriteError(taskID, version)
+	}
+
+	task.DeletedAt = nil
+	task.Version++
+	return task, nil
+}
+
+func (r *MongoTaskRepository) PurgeDeletedTasks(ctx context.Context, deletedBefore time.Time) (PurgeResult, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	expired := bson.D{{Key: "deletedat", Value: bson.D{{Key: "$ne", Value: nil}, {Key: "$lt", Value: deletedBefore}}}}
+
+	purged := []models.Task{}
+	cursor, err := r.collection.Find(ctx, expired)
+	if err != nil {
+		return PurgeResult{}, wrapMongoError(err, "failed to find expired tasks")
+	}
+	if err := cursor.All(ctx, &purged); err != nil {
+		return PurgeResult{}, wrapMongoError(err, "failed to decode expired tasks")
+	}
+	if len(purged) == 0 {
+		return PurgeResult{Purged: purged}, nil
+	}
+
+	ids := make([]int, len(purged))
+	for i, task := range purged {
+		ids[i] = task.ID
+	}
+	// re-check the expiry so a task restored in the meantime survives
+	filter := append(bson.D{{Key: "id", Value: bson.D{{Key: "$in", Value: ids}}}}, expired...)
+	if _, err := r.collection.DeleteMany(ctx, filter); err != nil {
+		return PurgeResult{}, wrapMongoError(err, "failed to purge expired tasks")
+	}
+
+	// leave out the tasks a restore saved from the purge, so callers do not
+	// clean up after tasks that are still live
+	var kept []int
+	survivors := r.collection.Distinct(ctx, "id", bson.D{{Key: "id", Value: bson.D{{Key: "$in", Value: ids}}}})
+	if err := survivors.Decode(&kept); err != nil {
+		return PurgeResult{}, wrapMongoError(err, "failed to look up purged tasks")
+	}
+	purged = slices.DeleteFunc(purged, func(task models.Task) bool { return slices.Contains(kept, task.ID) })
+	if len(purged) == 0 {
+		return PurgeResult{Purged: purged}, nil
+	}
+
+	unlinked, err := r.unlinkPurged(ctx, purged)
+	return PurgeResult{Purged: purged, Unlinked: unlinked}, err
+}
+
+// unlinkPurged removes links to the purged tasks from every other task,
+// bumping their versions as for any other write.
+func (r *MongoTaskRepository) unlinkPurged(ctx context.Context, purged []models.Task) ([]TaskChange, error) {
+	ids := make([]int, len(purged))
+	for i, task := range purged {
+		ids[i] = task.ID
+	}
+	linking := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "parentid", Value: bson.D{{Key: "$in", Value: ids}}}},
+		bson.D{{Key: "blockedby", Value: bson.D{{Key: "$in", Value: ids}}}},
+	}}}
+	changes, err := r.updateEach(ctx, linking, func(task models.Task) (models.Task, bool) {
+		return unlinkTask(task, purged)
+	})
+	if err != nil {
+		return changes, wrapMongoError(err, "failed to unlink purged tasks")
+	}
+	return changes, nil
+}
+
+// maxWriteAttempts bounds how often a conditional write the system makes
+// on its own, rather than for a client, is retried after losing a race.
+const maxWriteAttempts = 5
+
+// updateEach applies change to every task matching filter, live or in the
+// trash, with one write per task conditioned on its version, so each
+// change is returned with exactly the state it replaced. A task written
+// in the meantime is read again and changed from its new state.
+func (r *MongoTaskRepository) updateEach(ctx context.Context, filter bson.D, change func(models.Task) (models.Task, bool)) ([]TaskChange, error) {
+	var matched []models.Task
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &matched); err != nil {
+		return nil, err
+	}
+
+	var changes []TaskChange
+	for _, task := range matched {
+		for attempt := 1; ; attempt++ {
+			changed, ok := change(task)
+			if !ok {
+				break
+			}
+			result, err := r.collection.ReplaceOne(ctx, bson.D{{Key: "id", Value: task.ID}, {Key: "version", Value: task.Version}}, changed)
+			if err != nil {
+				return changes, err
+			}
+			if result.MatchedCount == 1 {
+				changes = append(changes, TaskChange{Previous: task, Task: changed})
+				break
+			}
+			if attempt == maxWriteAttempts {
+				return changes, concurrentWriteError(task.ID, task.Version)
+			}
+
+			err = r.collection.FindOne(ctx, bson.D{{Key: "id", Value: task.ID}}).Decode(&task)
+			if err == mongo.ErrNoDocuments {
+				break
+			}
+			if err != nil {
+				return changes, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+func (r *MongoTaskRepository) GetTaskGraph(ctx context.Context, id string, actor models.Actor) (models.TaskGraph, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return models.TaskGraph{}, err
+	}
+
+	task, err := r.findTask(ctx, taskID, actor)
+	if err != nil {
+		return models.TaskGraph{}, err
+	}
+	return buildTaskGraph(ctx, r, task)
+}
+
+// linkedTasks implements taskLinks.
+func (r *MongoTaskRepository) linkedTasks(ctx context.Context, ownerID int, ids []int) (map[int]models.Task, error) {
+	found := map[int]models.Task{}
+	if len(ids) == 0 {
+		return found, nil
+	}
+
+	filter := bson.D{
+		{Key: "id", Value: bson.D{{Key: "$in", Value: ids}}},
+		{Key: "ownerid", Value: ownerID},
+		notDeleted,
+	}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, wrapMongoError(err, "failed to fetch linked tasks")
+	}
+	var tasks []models.Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, wrapMongoError(err, "failed to decode linked tasks")
+	}
+	for _, task := range tasks {
+		found[task.ID] = task
+	}
+	return found, nil
+}
+
+// linkingTasks implements taskLinks.
+func (r *MongoTaskRepository) linkingTasks(ctx context.Context, ownerID int, ids []int) ([]models.Task, error) {
+	filter := bson.D{
+		{Key: "ownerid", Value: ownerID},
+		notDeleted,
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "parentid", Value: bson.D{{Key: "$in", Value: ids}}}},
+			bson.D{{Key: "blockedby", Value: bson.D{{Key: "$in", Value: ids}}}},
+		}},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, wrapMongoError(err, "failed to fetch linked tasks")
+	}
+	var found []models.Task
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, wrapMongoError(err, "failed to decode linked tasks")
+	}
+	return found, nil
+}
+
+// findTask loads a single task visible to actor.
+func (r *MongoTaskRepository) findTask(ctx context.Context, taskID int, actor models.Actor) (models.Task, error) {
+	var task models.Task
+	err := r.collection.FindOne(ctx, taskFilter(taskID, actor.OwnerScope())).Decode(&task)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Task{}, &customError.NotFoundError{ID: taskID}
+		}
+		return models.Task{}, wrapMongoError(err, "failed to fetch task %d", taskID)
+	}
+	return task, nil
+}
+
+// versionedTaskFilter matches task only while it is still live and at the
+// version it was read at.
+func versionedTaskFilter(task models.Task) bson.D {
+	return bson.D{{Key: "id", Value: task.ID}, {Key: "version", Value: task.Version}, notDeleted}
+}
+
+func (r *MongoTaskRepository) PutTask(ctx context.Context, task models.Task) (PutResult, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	if err := validateFixture(task); err != nil {
+		return PutResult{}, err
+	}
+
+	var existing *models.Task
+	var stored models.Task
+	err := r.collection.FindOne(ctx, bson.D{{Key: "id", Value: task.ID}}).Decode(&stored)
+	if err == nil {
+		existing = &stored
+	} else if err != mongo.ErrNoDocuments {
+		return PutResult{}, wrapMongoError(err, "failed to fetch task %d", task.ID)
+	}
+
+	changed, err := putTaskVersion(existing, &task)
+	if err != nil || !changed {
+		return PutResult{Previous: existing, Task: task}, err
+	}
+
+	if existing == nil {
+		_, err = r.collection.InsertOne(ctx, task)
+		if mongo.IsDuplicateKeyError(err) {
+			return PutResult{}, fixtureConflictError(task.ID)
+		}
+	} else {
+		var result *mongo.UpdateResult
+		filter := bson.D{{Key: "id", Value: task.ID}, {Key: "version", Value: existing.Version}}
+		result, err = r.collection.ReplaceOne(ctx, filter, task)
+		if err == nil && result.MatchedCount == 0 {
+			return PutResult{}, fixtureConflictError(task.ID)
+		}
+	}
+	if err != nil {
+		return PutResult{}, wrapMongoError(err, "failed to store task %d", task.ID)
+	}
+
+	// keep the counter from handing out the ID again
+	if err := syncCounter(ctx, r.counters, r.collection, taskCounterID); err != nil {
+		return PutResult{}, err
+	}
+	return PutResult{Previous: existing, Task: task, Written: true}, nil
+}
+
+func (r *MongoTaskRepository) AddATask(ctx context.Context, task models.Task) (models.Task, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	if err := validateNewTask(task); err != nil {
+		return models.Task{}, err
+	}
+	if err := checkReferences(ctx, r, r.users, nil, task); err != nil {
+		return models.Task{}, err
+	}
+	task.Version = 1
+	task.Attachments = nil
+	task.SeriesID, task.Occurrence = 0, 0
+	task.DeletedAt = nil
+
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		taskID, err := nextSequence(ctx, r.counters, taskCounterID)
+		if err != nil {
+			return models.Task{}, err
+		}
+
+		task.ID = taskID
+		_, err = r.collection.InsertOne(ctx, task)
+		if err == nil {
+			return task, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return models.Task{}, wrapMongoError(err, "failed to insert task %d", taskID)
+		}
+
+		// the counter fell behind the stored IDs; catch it up and try again
+		if err := syncCounter(ctx, r.counters, r.collection, taskCounterID); err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	return models.Task{}, &customError.ConflictError{Reason: "Could not allocate a unique task ID, please retry"}
+}
@@ -0,0 +1,210 @@
+package data
+
+import (
+	"context"
+	"task_manager/customError"
+	"task_manager/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type MongoUserRepository struct {
+	collection *mongo.Collection
+	counters   *mongo.Collection
+}
+
+const userCounterID = "users"
+
+func NewMongoUserRepository(ctx context.Context, db *mongo.Database) (*MongoUserRepository, error) {
+	repo := &MongoUserRepository{
+		collection: db.Collection("users"),
+		counters:   db.Collection("counters"),
+	}
+
+	err := createIndexes(ctx, repo.collection,
+		mongo.IndexModel{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		mongo.IndexModel{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syncCounter(ctx, repo.counters, repo.collection, userCounterID); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *MongoUserRepository) AddUser(ctx context.Context, user models.User) (models.User, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	user.Username = normalizeUsername(user.Username)
+	if err := validateUser(user); err != nil {
+		return models.User{}, err
+	}
+
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		userID, err := nextSequence(ctx, r.counters, userCounterID)
+		if err != nil {
+			return models.User{}, err
+		}
+
+		user.ID = userID
+		_, err = r.collection.InsertOne(ctx, user)
+		if err == nil {
+			return user, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return models.User{}, wrapMongoError(err, "failed to insert user %s", user.Username)
+		}
+
+		// the collision may be on the username rather than the id
+		if taken, err := r.usernameExists(ctx, user.Username); err != nil {
+			return models.User{}, err
+		} else if taken {
+			return models.User{}, usernameTakenError(user.Username)
+		}
+
+		if err := syncCounter(ctx, r.counters, r.collection, userCounterID); err != nil {
+			return models.User{}, err
+		}
+	}
+
+	return models.User{}, &customError.ConflictError{Reason: "Could not allocate a unique user ID, please retry"}
+}
+
+func (r *MongoUserRepository) GetUser(ctx context.Context, id int) (models.User, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	var user models.User
+	err := r.collection.FindOne(ctx, bson.D{{Key: "id", Value: id}}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.User{}, userNotFoundError(id)
+		}
+		return models.User{}, wrapMongoError(err, "failed to fetch user %d", id)
+	}
+	return user, nil
+}
+
+func (r *MongoUserRepository) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	var user models.User
+	filter := bson.D{{Key: "username", Value: normalizeUsername(username)}}
+
+	err := r.collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.User{}, &customError.NotFoundError{Resource: "User"}
+		}
+		return models.User{}, wrapMongoError(err, "failed to fetch user %s", username)
+	}
+	return user, nil
+}
+
+func (r *MongoUserRepository) FindUsers(ctx context.Context, ids []int) (map[int]models.User, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.D{{Key: "id", Value: bson.D{{Key: "$in", Value: ids}}}})
+	if err != nil {
+		return nil, wrapMongoError(err, "failed to fetch users")
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, wrapMongoError(err, "failed to decode users")
+	}
+
+	found := make(map[int]models.User, len(users))
+	for _, user := range users {
+		found[user.ID] = user
+	}
+	return found, nil
+}
+
+func (r *MongoUserRepository) ListUsers(ctx context.Context, offset, limit int) (models.UserPage, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	offset, limit, err := normalizePage(offset, limit)
+	if err != nil {
+		return models.UserPage{}, err
+	}
+
+	total, err := r.collection.CountDocuments(ctx, bson.D{})
+	if err != nil {
+		return models.UserPage{}, wrapMongoError(err, "failed to count users")
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "id", Value: 1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, bson.D{}, opts)
+	if err != nil {
+		return models.UserPage{}, wrapMongoError(err, "failed to fetch users")
+	}
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return models.UserPage{}, wrapMongoError(err, "failed to decode users")
+	}
+
+	return models.UserPage{Users: users, Total: total, Offset: offset, Limit: limit}, nil
+}
+
+func (r *MongoUserRepository) UpdateUser(ctx context.Context, user models.User) (models.User, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	user.Username = normalizeUsername(user.Username)
+	if err := validateUser(user); err != nil {
+		return models.User{}, err
+	}
+
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "username", Value: user.Username},
+		{Key: "passwordhash", Value: user.PasswordHash},
+		{Key: "role", Value: user.Role},
+		{Key: "email", Value: user.Email},
+	}}}
+	result, err := r.collection.UpdateOne(ctx, bson.D{{Key: "id", Value: user.ID}}, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return models.User{}, usernameTakenError(user.Username)
+	}
+	if err != nil {
+		return models.User{}, wrapMongoError(err, "failed to update user %d", user.ID)
+	}
+	if result.MatchedCount == 0 {
+		return models.User{}, userNotFoundError(user.ID)
+	}
+	return user, nil
+}
+
+func (r *MongoUserRepository) DeleteUser(ctx context.Context, id int) error {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.D{{Key: "id", Value: id}})
+	if err != nil {
+		return wrapMongoError(err, "failed to delete user %d", id)
+	}
+	if result.DeletedCount == 0 {
+		return userNotFoundError(id)
+	}
+	return nil
+}
+
+func (r *MongoUserRepository) usernameExists(ctx context.Context, username string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.D{{Key: "username", Value: username}})
+	if err != nil {
+		return false, wrapMongoError(err, "failed to look up user %s", username)
+	}
+	return count > 0, nil
+}
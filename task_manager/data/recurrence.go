@@ -0,0 +1,280 @@
+package data
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"task_manager/models"
+	"time"
+)
+
+// MaxRecurrenceLength bounds the recurrence rule of a task.
+const MaxRecurrenceLength = 256
+
+// maxRecurrenceInterval bounds INTERVAL, which keeps the search for the
+// next date of a rule short.
+const maxRecurrenceInterval = 1000
+
+// maxRecurrenceSteps bounds how many periods the next date of a rule is
+// looked for in; a rule like "every 29 February" finds one within a few.
+const maxRecurrenceSteps = 1000
+
+var recurrenceWeekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// SeriesAdvance is the outcome of AdvanceSeries: the latest occurrence of
+// a series before and after its rule moved on, and the occurrence that
+// follows it, which is nil if the rule allows none.
+type SeriesAdvance struct {
+	Previous models.Task
+	Head     models.Task
+	Next     *models.Task
+	// Created is false if Next was already stored by an earlier call.
+	Created bool
+}
+
+// recurrenceRule is the subset of RFC 5545 recurrence rules tasks support:
+// FREQ with INTERVAL, COUNT or UNTIL, and BYDAY for weekly or BYMONTHDAY
+// for monthly rules. Dates are stepped from the due date of the latest
+// occurrence, keeping its time of day, in UTC.
+type recurrenceRule struct {
+	freq       string
+	interval   int
+	count      int       // occurrences in the series, counting the first task; 0 for no limit
+	until      time.Time // last allowed due date; zero for no limit
+	byDay      []time.Weekday
+	byMonthDay []int // negative days count from the end of the month
+}
+
+// parseRecurrence reads a rule such as "FREQ=MONTHLY;BYMONTHDAY=-1",
+// optionally prefixed by "RRULE:". Names and values are case-insensitive.
+func parseRecurrence(text string) (recurrenceRule, error) {
+	if len(text) > MaxRecurrenceLength {
+		return recurrenceRule{}, fmt.Errorf("can not be longer than %d characters", MaxRecurrenceLength)
+	}
+	text = strings.TrimSpace(text)
+	if len(text) >= len("RRULE:") && strings.EqualFold(text[:len("RRULE:")], "RRULE:") {
+		text = text[len("RRULE:"):]
+	}
+
+	rule := recurrenceRule{interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(text, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name, value = strings.ToUpper(strings.TrimSpace(name)), strings.ToUpper(strings.TrimSpace(value))
+		if !ok || name == "" || value == "" {
+			return recurrenceRule{}, fmt.Errorf("%q is not a NAME=VALUE part", part)
+		}
+		if seen[name] {
+			return recurrenceRule{}, fmt.Errorf("%s is given more than once", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			rule.freq = value
+			if !slices.Contains([]string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}, value) {
+				err = fmt.Errorf("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(value)
+			if err != nil || rule.interval < 1 || rule.interval > maxRecurrenceInterval {
+				err = fmt.Errorf("INTERVAL must be a number from 1 to %d", maxRecurrenceInterval)
+			}
+		case "COUNT":
+			rule.count, err = strconv.Atoi(value)
+			if err != nil || rule.count < 1 {
+				err = fmt.Errorf("COUNT must be a positive number")
+			}
+		case "UNTIL":
+			rule.until, err = parseRecurrenceUntil(value)
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := recurrenceWeekdays[strings.TrimSpace(day)]
+				if !ok {
+					return recurrenceRule{}, fmt.Errorf("BYDAY takes weekdays such as MO or TU, not %q", day)
+				}
+				rule.byDay = append(rule.byDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(day))
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return recurrenceRule{}, fmt.Errorf("BYMONTHDAY takes days from 1 to 31 or -31 to -1, not %q", day)
+				}
+				rule.byMonthDay = append(rule.byMonthDay, n)
+			}
+		default:
+			err = fmt.Errorf("%s is not supported", name)
+		}
+		if err != nil {
+			return recurrenceRule{}, err
+		}
+	}
+
+	switch {
+	case rule.freq == "":
+		return recurrenceRule{}, fmt.Errorf("FREQ is required")
+	case rule.count > 0 && !rule.until.IsZero():
+		return recurrenceRule{}, fmt.Errorf("COUNT and UNTIL can not be combined")
+	case len(rule.byDay) > 0 && rule.freq != "WEEKLY":
+		return recurrenceRule{}, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	case len(rule.byMonthDay) > 0 && rule.freq != "MONTHLY":
+		return recurrenceRule{}, fmt.Errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	slices.SortFunc(rule.byDay, func(a, b time.Weekday) int { return weekdayOffset(a) - weekdayOffset(b) })
+	rule.byDay = slices.Compact(rule.byDay)
+	return rule, nil
+}
+
+// parseRecurrenceUntil reads UNTIL as a date, which allows the whole day,
+// or as a UTC date-time.
+func parseRecurrenceUntil(value string) (time.Time, error) {
+	if date, err := time.Parse("20060102", value); err == nil {
+		return date.Add(24*time.Hour - time.Millisecond), nil
+	}
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+	return time.Time{}, fmt.Errorf("UNTIL must be a date such as 20251231 or a UTC time such as 20251231T170000Z")
+}
+
+// weekdayOffset numbers the days of the week from Monday, which starts
+// the week in RFC 5545 by default.
+func weekdayOffset(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+// next returns the first date after prev the rule allows, and false if
+// there is none within maxRecurrenceSteps periods.
+func (r recurrenceRule) next(prev time.Time) (time.Time, bool) {
+	prev = prev.UTC()
+	switch r.freq {
+	case "DAILY":
+		return prev.AddDate(0, 0, r.interval), true
+	case "WEEKLY":
+		if len(r.byDay) == 0 {
+			return prev.AddDate(0, 0, 7*r.interval), true
+		}
+		offset := weekdayOffset(prev.Weekday())
+		for _, day := range r.byDay {
+			if weekdayOffset(day) > offset {
+				return prev.AddDate(0, 0, weekdayOffset(day)-offset), true
+			}
+		}
+		weekStart := prev.AddDate(0, 0, -offset)
+		return weekStart.AddDate(0, 0, 7*r.interval+weekdayOffset(r.byDay[0])), true
+	case "MONTHLY":
+		days := r.byMonthDay
+		if len(days) == 0 {
+			days = []int{prev.Day()}
+		}
+		for step := 0; step <= maxRecurrenceSteps; step++ {
+			// the day is set separately so months shorter than prev's day
+			// do not spill over into the next month
+			month := time.Date(prev.Year(), prev.Month()+time.Month(step*r.interval), 1,
+				prev.Hour(), prev.Minute(), prev.Second(), prev.Nanosecond(), time.UTC)
+			for _, day := range monthDays(month, days) {
+				if step > 0 || day > prev.Day() {
+					return month.AddDate(0, 0, day-1), true
+				}
+			}
+		}
+	case "YEARLY":
+		for step := 1; step <= maxRecurrenceSteps; step++ {
+			date := time.Date(prev.Year()+step*r.interval, prev.Month(), prev.Day(),
+				prev.Hour(), prev.Minute(), prev.Second(), prev.Nanosecond(), time.UTC)
+			// 29 February only exists in leap years
+			if date.Day() == prev.Day() {
+				return date, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// monthDays resolves BYMONTHDAY values to the sorted days of month they
+// stand for, skipping days the month does not have.
+func monthDays(month time.Time, byMonthDay []int) []int {
+	length := month.AddDate(0, 1, -1).Day()
+	var days []int
+	for _, day := range byMonthDay {
+		if day < 0 {
+			day += length + 1
+		}
+		if day >= 1 && day <= length {
+			days = append(days, day)
+		}
+	}
+	slices.Sort(days)
+	return slices.Compact(days)
+}
+
+// seriesOf returns the ID of the first task of the series task belongs to.
+func seriesOf(task models.Task) int {
+	if task.SeriesID != 0 {
+		return task.SeriesID
+	}
+	return task.ID
+}
+
+// occurrenceOf returns the number of task in its series.
+func occurrenceOf(task models.Task) int {
+	return max(task.Occurrence, 1)
+}
+
+// NextOccurrence returns the due date of the occurrence that follows task
+// in its series, and false if task carries no valid rule or the rule
+// allows no more occurrences.
+func NextOccurrence(task models.Task) (time.Time, bool) {
+	if task.Recurrence == "" {
+		return time.Time{}, false
+	}
+	rule, err := parseRecurrence(task.Recurrence)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if rule.count > 0 && occurrenceOf(task) >= rule.count {
+		return time.Time{}, false
+	}
+	due, ok := rule.next(task.DueDate)
+	if !ok || (!rule.until.IsZero() && due.After(rule.until)) {
+		return time.Time{}, false
+	}
+	return due, true
+}
+
+// newOccurrence builds the occurrence after head, due at due and still
+// without an ID. It copies what describes the work, who does it and when
+// to remind them, and takes over the rule, but starts in the first initial
+// status without blockers or attachments.
+func newOccurrence(head models.Task, due time.Time) models.Task {
+	return models.Task{
+		Title:       head.Title,
+		Description: head.Description,
+		DueDate:     due,
+		Status:      workflow.Initial[0],
+		OwnerID:     head.OwnerID,
+		Tags:        slices.Clone(head.Tags),
+		ParentID:    head.ParentID,
+		Assignees:   slices.Clone(head.Assignees),
+		Watchers:    slices.Clone(head.Watchers),
+		Reminders:   slices.Clone(head.Reminders),
+		Recurrence:  head.Recurrence,
+		SeriesID:    seriesOf(head),
+		Occurrence:  occurrenceOf(head) + 1,
+		Version:     1,
+	}
+}
+
+// moveRuleOff returns head without its rule, which has moved on to the
+// next occurrence, and its version bumped.
+func moveRuleOff(head models.Task) models.Task {
+	head.Recurrence = ""
+	head.Version++
+	return head
+}
@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"strconv"
+	"task_manager/models"
+	"testing"
+	"time"
+)
+
+func date(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestRecurrenceRuleNext(t *testing.T) {
+	tests := []struct {
+		name, rule, prev, want string
+	}{
+		{"daily", "FREQ=DAILY", "2025-01-09T10:00:00Z", "2025-01-10T10:00:00Z"},
+		{"every other day", "FREQ=DAILY;INTERVAL=2", "2025-12-31T10:00:00Z", "2026-01-02T10:00:00Z"},
+		// rules step in UTC, so a local due date keeps its UTC time of day
+		// across a daylight saving change
+		{"daily into summer time", "FREQ=DAILY", "2025-03-29T09:00:00+01:00", "2025-03-30T08:00:00Z"},
+		{"weekly out of summer time", "RRULE:FREQ=WEEKLY", "2025-10-22T09:00:00+02:00", "2025-10-29T07:00:00Z"},
+
+		{"weekly", "FREQ=WEEKLY", "2025-01-08T10:00:00Z", "2025-01-15T10:00:00Z"},
+		{"later day this week", "FREQ=WEEKLY;BYDAY=MO,WE,FR", "2025-01-08T10:00:00Z", "2025-01-10T10:00:00Z"},
+		{"first day next week", "FREQ=WEEKLY;BYDAY=FR,MO,WE", "2025-01-10T10:00:00Z", "2025-01-13T10:00:00Z"},
+		{"first day in two weeks", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "2025-01-10T10:00:00Z", "2025-01-20T10:00:00Z"},
+		{"sunday ends the week", "FREQ=WEEKLY;BYDAY=MO,SU", "2025-01-12T10:00:00Z", "2025-01-13T10:00:00Z"},
+		{"off-rule day", "FREQ=WEEKLY;BYDAY=TU", "2025-01-08T10:00:00Z", "2025-01-14T10:00:00Z"},
+
+		{"monthly", "FREQ=MONTHLY", "2025-01-15T10:00:00Z", "2025-02-15T10:00:00Z"},
+		{"day 31 skips short months", "FREQ=MONTHLY", "2025-01-31T10:00:00Z", "2025-03-31T10:00:00Z"},
+		{"last day of month", "FREQ=MONTHLY;BYMONTHDAY=-1", "2025-01-31T10:00:00Z", "2025-02-28T10:00:00Z"},
+		{"last day of leap february", "FREQ=MONTHLY;BYMONTHDAY=-1", "2024-01-31T10:00:00Z", "2024-02-29T10:00:00Z"},
+		{"days the month lacks", "FREQ=MONTHLY;BYMONTHDAY=30,31", "2025-01-31T10:00:00Z", "2025-03-30T10:00:00Z"},
+		{"later day this month", "FREQ=MONTHLY;BYMONTHDAY=1,15", "2025-01-01T10:00:00Z", "2025-01-15T10:00:00Z"},
+		{"quarterly skips 30 february", "FREQ=MONTHLY;INTERVAL=3", "2025-11-30T10:00:00Z", "2026-05-30T10:00:00Z"},
+
+		{"yearly", "FREQ=YEARLY", "2025-06-01T10:00:00Z", "2026-06-01T10:00:00Z"},
+		{"29 february", "FREQ=YEARLY", "2024-02-29T10:00:00Z", "2028-02-29T10:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := parseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := rule.next(date(t, tt.prev))
+			if want := date(t, tt.want); !ok || !got.Equal(want) {
+				t.Errorf("next(%s) = %s, %v, want %s", tt.prev, got, ok, want)
+			}
+		})
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	tests := []struct {
+		name       string
+		rule       string
+		occurrence int
+		due, want  string // want is empty if no occurrence follows
+	}{
+		{"no rule", "", 0, "2025-01-09T10:00:00Z", ""},
+		{"invalid rule", "FREQ=HOURLY", 0, "2025-01-09T10:00:00Z", ""},
+		{"first task counts as one", "FREQ=DAILY;COUNT=2", 0, "2025-01-09T10:00:00Z", "2025-01-10T10:00:00Z"},
+		{"before the count", "FREQ=DAILY;COUNT=3", 2, "2025-01-09T10:00:00Z", "2025-01-10T10:00:00Z"},
+		{"count reached", "FREQ=DAILY;COUNT=3", 3, "2025-01-09T10:00:00Z", ""},
+		{"until date includes the day", "FREQ=DAILY;UNTIL=20250110", 1, "2025-01-09T10:00:00Z", "2025-01-10T10:00:00Z"},
+		{"until date passed", "FREQ=DAILY;UNTIL=20250110", 2, "2025-01-10T10:00:00Z", ""},
+		{"until time", "FREQ=DAILY;UNTIL=20250110T100000Z", 1, "2025-01-09T10:00:00Z", "2025-01-10T10:00:00Z"},
+		{"until time passed", "FREQ=DAILY;UNTIL=20250110T095959Z", 1, "2025-01-09T10:00:00Z", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := models.Task{Recurrence: tt.rule, Occurrence: tt.occurrence, DueDate: date(t, tt.due)}
+			got, ok := NextOccurrence(task)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("got %s, want none", got)
+				}
+				return
+			}
+			if want := date(t, tt.want); !ok || !got.Equal(want) {
+				t.Errorf("got %s, %v, want %s", got, ok, want)
+			}
+		})
+	}
+}
+
+func TestParseRecurrenceRejects(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20250110",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=DAILY;UNTIL=tomorrow",
+	} {
+		if _, err := parseRecurrence(rule); err == nil {
+			t.Errorf("parseRecurrence(%q) accepted the rule", rule)
+		}
+	}
+}
+
+func TestRestoreTaskKeepsOneSeriesHead(t *testing.T) {
+	ctx := context.Background()
+	tasks := NewAuditedTaskRepository(NewMemoryTaskRepository(false, NewMemoryUserRepository()), NewMemoryHistoryRepository())
+	first, err := tasks.AddATask(ctx, models.Task{
+		Title:       "Water the plants",
+		Description: "Every day",
+		DueDate:     time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond),
+		Status:      models.Pending,
+		OwnerID:     linkOwner.UserID,
+		Recurrence:  "FREQ=DAILY",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	advance, err := tasks.AdvanceSeries(ctx, first)
+	if err != nil || !advance.Created {
+		t.Fatalf("advancing the series got %+v (%v), want a new occurrence", advance, err)
+	}
+
+	// the series has moved on, so the first task stays without its rule
+	restored, err := tasks.RestoreTask(ctx, strconv.Itoa(first.ID), linkOwner, AnyVersion, first.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Recurrence != "" {
+		t.Errorf("restoring an older occurrence brought back the rule %q", restored.Recurrence)
+	}
+
+	// the head itself gets its rule back once it was taken off
+	head := *advance.Next
+	cleared := head
+	cleared.Recurrence = ""
+	if _, err := tasks.UpdateTask(ctx, strconv.Itoa(head.ID), linkOwner, head.Version, cleared); err != nil {
+		t.Fatal(err)
+	}
+	restored, err = tasks.RestoreTask(ctx, strconv.Itoa(head.ID), linkOwner, AnyVersion, head.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Recurrence != head.Recurrence {
+		t.Errorf("restoring the head left the rule %q, want %q", restored.Recurrence, head.Recurrence)
+	}
+}
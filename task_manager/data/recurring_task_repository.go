@@ -0,0 +1,89 @@
+package data
+
+import (
+	"context"
+	"log"
+	"task_manager/models"
+)
+
+type recurringTaskRepository struct {
+	AuditedTaskRepository
+}
+
+// NewRecurringTaskRepository wraps tasks so that closing the latest
+// occurrence of a recurring series, through any write that can change a
+// status, creates the next occurrence right away. The write itself has
+// already succeeded at that point, so a failure to continue the series is
+// logged rather than returned; the recurrence scheduler picks up closed
+// occurrences that still carry their rule.
+func NewRecurringTaskRepository(tasks AuditedTaskRepository) AuditedTaskRepository {
+	return &recurringTaskRepository{AuditedTaskRepository: tasks}
+}
+
+func (r *recurringTaskRepository) AddATask(ctx context.Context, task models.Task) (models.Task, error) {
+	created, err := r.AuditedTaskRepository.AddATask(ctx, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return r.continueSeries(ctx, created), nil
+}
+
+func (r *recurringTaskRepository) UpdateTask(ctx context.Context, id string, actor models.Actor, version int, updatedTask models.Task) (models.Task, error) {
+	task, err := r.AuditedTaskRepository.UpdateTask(ctx, id, actor, version, updatedTask)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return r.continueSeries(ctx, task), nil
+}
+
+func (r *recurringTaskRepository) PatchTask(ctx context.Context, id string, actor models.Actor, version int, format PatchFormat, patch []byte) (models.Task, error) {
+	task, err := r.AuditedTaskRepository.PatchTask(ctx, id, actor, version, format, patch)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return r.continueSeries(ctx, task), nil
+}
+
+func (r *recurringTaskRepository) RestoreDeletedTask(ctx context.Context, id string, actor models.Actor, version int) (models.Task, error) {
+	task, err := r.AuditedTaskRepository.RestoreDeletedTask(ctx, id, actor, version)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return r.continueSeries(ctx, task), nil
+}
+
+func (r *recurringTaskRepository) RestoreTask(ctx context.Context, id string, actor models.Actor, version, fromVersion int) (models.Task, error) {
+	task, err := r.AuditedTaskRepository.RestoreTask(ctx, id, actor, version, fromVersion)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return r.continueSeries(ctx, task), nil
+}
+
+func (r *recurringTaskRepository) BulkWrite(ctx context.Context, actor models.Actor, ops []models.BulkOperation) ([]BulkResult, error) {
+	results, err := r.AuditedTaskRepository.BulkWrite(ctx, actor, ops)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		if results[i].Err == nil && results[i].Task != nil {
+			task := r.continueSeries(ctx, *results[i].Task)
+			results[i].Task = &task
+		}
+	}
+	return results, nil
+}
+
+// continueSeries advances the series of task if it is a closed latest
+// occurrence and returns task as it is stored afterwards.
+func (r *recurringTaskRepository) continueSeries(ctx context.Context, task models.Task) models.Task {
+	if task.Recurrence == "" || !task.Status.IsClosed() {
+		return task
+	}
+	advance, err := r.AdvanceSeries(context.WithoutCancel(ctx), task)
+	if err != nil {
+		log.Printf("Error continuing the series of task %d: %v", task.ID, err)
+		return task
+	}
+	return advance.Head
+}
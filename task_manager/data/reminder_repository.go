@@ -0,0 +1,54 @@
+package data
+
+import (
+	"context"
+	"task_manager/customError"
+	"task_manager/models"
+	"time"
+)
+
+// ReminderRepository keeps a record of every reminder sent, or being sent,
+// through every channel. Like CommentRepository it does not know who may
+// see a task, so callers must check that first.
+type ReminderRepository interface {
+	// AddDelivery stores a new pending delivery and returns it with its ID
+	// and creation time set. If the reminder already has a delivery
+	// through the channel, that one is returned with false instead; this
+	// is what keeps a reminder from going out twice.
+	AddDelivery(ctx context.Context, delivery models.ReminderDelivery) (models.ReminderDelivery, bool, error)
+	// ClaimDeliveries returns up to limit pending deliveries whose next
+	// attempt is due by now, oldest first, with their attempts counted and
+	// their next attempt moved to leaseUntil. A delivery is claimed by one
+	// caller only, so several dispatchers can run side by side, and is
+	// tried again once the lease runs out if its sender never reports back.
+	ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.ReminderDelivery, error)
+	// UpdateDelivery stores the outcome of an attempt: the status, next
+	// attempt, error and send time of delivery.
+	UpdateDelivery(ctx context.Context, delivery models.ReminderDelivery) error
+	// GetTaskDeliveries lists the deliveries of a task, oldest first.
+	GetTaskDeliveries(ctx context.Context, taskID int) ([]models.ReminderDelivery, error)
+	// DeleteTaskDeliveries removes the deliveries of the given tasks, once
+	// they have been purged, and returns how many it removed.
+	DeleteTaskDeliveries(ctx context.Context, taskIDs []int) (int64, error)
+}
+
+// sameReminder reports whether two deliveries are for the same reminder
+// through the same channel.
+func sameReminder(a, b models.ReminderDelivery) bool {
+	return a.TaskID == b.TaskID && a.Offset == b.Offset && a.DueDate.Equal(b.DueDate) && a.Channel == b.Channel
+}
+
+// newDelivery returns delivery as it is first stored, still without an ID.
+func newDelivery(delivery models.ReminderDelivery) models.ReminderDelivery {
+	delivery.ID = 0
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.LastError = ""
+	delivery.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	delivery.SentAt = nil
+	return delivery
+}
+
+func deliveryNotFoundError(deliveryID int) error {
+	return &customError.NotFoundError{Resource: "Reminder delivery", ID: deliveryID}
+}
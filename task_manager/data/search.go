@@ -0,0 +1,246 @@
+package data
+
+import (
+	"html"
+	"slices"
+	"strings"
+	"task_manager/customError"
+	"task_manager/models"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxSearchLength bounds the text of a search query.
+const MaxSearchLength = 256
+
+// searchWeights favour matches in the title over the description, in both
+// the Mongo text index and the in-memory scoring.
+var searchWeights = map[string]int{"title": 3, "description": 1}
+
+// snippetLength is roughly how many bytes of the description a highlight
+// shows around its first match.
+const snippetLength = 160
+
+// searchStopWords are skipped like MongoDB skips English stop words, so
+// they match nothing in either backend.
+var searchStopWords = []string{
+	"a", "an", "and", "are", "as", "at", "be", "by", "for", "from", "in", "is",
+	"it", "of", "on", "or", "that", "the", "to", "was", "with",
+}
+
+// searchQuery is a parsed search in MongoDB's $text syntax: words match if
+// any of them is present, "quoted phrases" must all be present and
+// -negated words or phrases must not be.
+type searchQuery struct {
+	terms           []string // stemmed
+	phrases         []string // lowercased
+	excluded        []string // stemmed
+	excludedPhrases []string // lowercased
+}
+
+func parseSearch(text string) (searchQuery, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return searchQuery{}, &customError.BadRequestError{Reason: "Search text q can not be empty"}
+	}
+	if len(text) > MaxSearchLength {
+		return searchQuery{}, &customError.BadRequestError{Reason: "Search text q can not be longer than 256 characters"}
+	}
+
+	var search searchQuery
+	rest := text
+	for rest != "" {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		negated := strings.HasPrefix(rest, "-")
+		if negated {
+			rest = rest[1:]
+		}
+
+		if strings.HasPrefix(rest, `"`) {
+			phrase, after, _ := strings.Cut(rest[1:], `"`)
+			rest = after
+			phrase = strings.ToLower(strings.Join(strings.Fields(phrase), " "))
+			if phrase == "" {
+				continue
+			}
+			if negated {
+				search.excludedPhrases = append(search.excludedPhrases, phrase)
+			} else {
+				search.phrases = append(search.phrases, phrase)
+			}
+			continue
+		}
+
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = rest[end:]
+		for _, span := range wordSpans(word) {
+			stem := searchStem(word[span[0]:span[1]])
+			if stem == "" {
+				continue
+			}
+			if negated {
+				search.excluded = append(search.excluded, stem)
+			} else {
+				search.terms = append(search.terms, stem)
+			}
+		}
+	}
+
+	if len(search.terms) == 0 && len(search.phrases) == 0 {
+		return searchQuery{}, &customError.BadRequestError{Reason: "Search text q must contain a word or phrase to look for"}
+	}
+	return search, nil
+}
+
+// wordSpans returns the byte offsets of the words, runs of letters and
+// digits, in text.
+func wordSpans(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
+
+// searchStem lowercases a word and strips common English suffixes, a much
+// simpler take on the stemming MongoDB applies, so "reports" and
+// "reporting" both find "report". Stop words stem to "".
+func searchStem(word string) string {
+	word = strings.ToLower(word)
+	if slices.Contains(searchStopWords, word) {
+		return ""
+	}
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if stem, ok := strings.CutSuffix(word, suffix); ok && utf8.RuneCountInString(stem) >= 3 {
+			return stem
+		}
+	}
+	return word
+}
+
+// matches reports whether a stemmed word of a task is one being looked
+// for, either on its own or as part of a phrase.
+func (s searchQuery) matches(stem string) bool {
+	return slices.Contains(s.terms, stem) || slices.ContainsFunc(s.phrases, func(phrase string) bool {
+		return slices.ContainsFunc(strings.Fields(phrase), func(word string) bool { return searchStem(word) == stem })
+	})
+}
+
+// scoreTask returns the relevance of task for the in-memory backend, or 0
+// if it does not match: every matched word counts with the weight of its
+// field, damped by the length of the field like MongoDB's text score.
+func (s searchQuery) scoreTask(task models.Task) float64 {
+	fields := map[string]string{"title": task.Title, "description": task.Description}
+	text := strings.ToLower(task.Title + "\n" + task.Description)
+	for _, phrase := range s.phrases {
+		if !strings.Contains(text, phrase) {
+			return 0
+		}
+	}
+	for _, phrase := range s.excludedPhrases {
+		if strings.Contains(text, phrase) {
+			return 0
+		}
+	}
+
+	score := 0.0
+	for name, value := range fields {
+		spans := wordSpans(value)
+		matched := 0
+		for _, span := range spans {
+			stem := searchStem(value[span[0]:span[1]])
+			if slices.Contains(s.excluded, stem) {
+				return 0
+			}
+			if stem != "" && s.matches(stem) {
+				matched++
+			}
+		}
+		if matched > 0 {
+			score += float64(searchWeights[name]*matched) / (float64(len(spans))/2 + 0.5)
+		}
+	}
+	return score
+}
+
+// highlightTask builds the highlights of a search hit. It is used by both
+// backends, so the snippets look the same whichever one found the task.
+func (s searchQuery) highlightTask(task models.Task) map[string]string {
+	highlights := map[string]string{}
+	if title, ok := s.highlight(task.Title, len(task.Title)); ok {
+		highlights["title"] = title
+	}
+	if description, ok := s.highlight(task.Description, snippetLength); ok {
+		highlights["description"] = description
+	}
+	return highlights
+}
+
+// highlight escapes text and wraps the matched words in <mark>, cutting it
+// down to about maxLength bytes around the first match.
+func (s searchQuery) highlight(text string, maxLength int) (string, bool) {
+	spans := wordSpans(text)
+	first := slices.IndexFunc(spans, func(span [2]int) bool {
+		stem := searchStem(text[span[0]:span[1]])
+		return stem != "" && s.matches(stem)
+	})
+	if first < 0 {
+		return "", false
+	}
+
+	// start a few words before the first match and stop once the snippet
+	// is long enough, always cutting between words
+	start, end := 0, len(text)
+	if len(text) > maxLength {
+		from := max(first-5, 0)
+		to := from
+		for to+1 < len(spans) && spans[to+1][1]-spans[from][0] <= maxLength {
+			to++
+		}
+		to = max(to, first)
+		if from > 0 {
+			start = spans[from][0]
+		}
+		if to < len(spans)-1 {
+			end = spans[to][1]
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	last := start
+	for _, span := range spans {
+		if span[0] < start || span[1] > end {
+			continue
+		}
+		stem := searchStem(text[span[0]:span[1]])
+		if stem == "" || !s.matches(stem) {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[last:span[0]]))
+		b.WriteString("<mark>" + html.EscapeString(text[span[0]:span[1]]) + "</mark>")
+		last = span[1]
+	}
+	b.WriteString(html.EscapeString(text[last:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
@@ -0,0 +1,116 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"task_manager/config"
+)
+
+// Store bundles the repositories of one storage backend so they share a
+// single connection and are closed together.
+type Store struct {
+	Tasks     AuditedTaskRepository
+	Users     UserRepository
+	Comments  CommentRepository
+	Blobs     BlobStore // the bytes of the attachments listed on tasks
+	Reminders ReminderRepository
+	ping      func(ctx context.Context) error
+	close     func()
+}
+
+// NewStore builds the backend selected by cfg.Backend, with attachments
+// kept in the backend selected by attachments.Backend. ctx bounds the
+// connection and the startup work (indexes, migrations) of the backend.
+func NewStore(ctx context.Context, cfg config.Storage, attachments config.Attachments) (*Store, error) {
+	switch cfg.Backend {
+	case config.MongoBackend:
+		return newMongoStore(ctx, cfg, attachments)
+	case config.MemoryBackend:
+		blobs, err := newBlobStore(attachments, nil)
+		if err != nil {
+			return nil, err
+		}
+		users := NewMemoryUserRepository()
+		return &Store{
+			Tasks:     NewRecurringTaskRepository(NewAuditedTaskRepository(NewMemoryTaskRepository(cfg.Seed, users), NewMemoryHistoryRepository())),
+			Users:     users,
+			Comments:  NewMemoryCommentRepository(),
+			Blobs:     blobs,
+			Reminders: NewMemoryReminderRepository(),
+			ping:      func(context.Context) error { return nil },
+			close:     func() {},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+func newMongoStore(ctx context.Context, cfg config.Storage, attachments config.Attachments) (*Store, error) {
+	client, db, err := InitMongo(ctx, cfg.Mongo.URI, cfg.Mongo.Database)
+	if err != nil {
+		return nil, err
+	}
+	closeClient := func() { _ = client.Disconnect(context.Background()) }
+
+	users, err := NewMongoUserRepository(ctx, db)
+	if err != nil {
+		closeClient()
+		return nil, err
+	}
+
+	tasks, err := NewMongoTaskRepository(ctx, db, cfg.Mongo.TasksCollection, cfg.Seed, users)
+	if err != nil {
+		closeClient()
+		return nil, err
+	}
+
+	history, err := NewMongoHistoryRepository(ctx, db)
+	if err != nil {
+		closeClient()
+		return nil, err
+	}
+
+	comments, err := NewMongoCommentRepository(ctx, db)
+	if err != nil {
+		closeClient()
+		return nil, err
+	}
+
+	blobs, err := newBlobStore(attachments, db)
+	if err != nil {
+		closeClient()
+		return nil, err
+	}
+
+	reminders, err := NewMongoReminderRepository(ctx, db)
+	if err != nil {
+		closeClient()
+		return nil, err
+	}
+
+	return &Store{
+		Tasks:     NewRecurringTaskRepository(NewAuditedTaskRepository(tasks, history)),
+		Users:     users,
+		Comments:  comments,
+		Blobs:     blobs,
+		Reminders: reminders,
+		ping: func(ctx context.Context) error {
+			ctx, cancel := readContext(ctx)
+			defer cancel()
+			if err := client.Ping(ctx, nil); err != nil {
+				return wrapMongoError(err, "failed to reach MongoDB")
+			}
+			return nil
+		},
+		close: closeClient,
+	}, nil
+}
+
+// Ping checks that the backend can serve requests.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.ping(ctx)
+}
+
+func (s *Store) Close() {
+	s.close()
+}
@@ -0,0 +1,22 @@
+package data
+
+import (
+	"slices"
+	"task_manager/models"
+)
+
+// retagTask returns task with the tags in add added and those in remove
+// taken away, and whether that changed anything. The result still has to
+// be validated.
+func retagTask(task models.Task, add, remove []string) (models.Task, bool) {
+	remove = models.NormalizeTags(remove)
+	tags := models.NormalizeTags(append(slices.Clone(task.Tags), add...))
+	tags = slices.DeleteFunc(tags, func(tag string) bool { return slices.Contains(remove, tag) })
+	if len(tags) == 0 {
+		tags = nil
+	}
+
+	changed := !slices.Equal(tags, task.Tags)
+	task.Tags = tags
+	return task, changed
+}
@@ -0,0 +1,123 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"task_manager/customError"
+	"task_manager/models"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type PatchFormat int
+
+const (
+	MergePatch PatchFormat = iota // RFC 7396, application/merge-patch+json
+	JSONPatch                     // RFC 6902, application/json-patch+json
+)
+
+// applyTaskPatch applies a patch document to the JSON form of task and
+// decodes the result back. The ID, owner, version, series, attachments and
+// trash state can not be changed through a patch; the caller still has to
+// validate the result.
+func applyTaskPatch(task models.Task, format PatchFormat, patch []byte) (models.Task, error) {
+	original, err := json.Marshal(task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	var patched []byte
+	switch format {
+	case MergePatch:
+		patched, err = jsonpatch.MergePatch(original, patch)
+	case JSONPatch:
+		var operations jsonpatch.Patch
+		operations, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			patched, err = operations.Apply(original)
+		}
+	default:
+		return models.Task{}, &customError.BadRequestError{Reason: "Unsupported patch format"}
+	}
+	if err != nil {
+		return models.Task{}, &customError.BadRequestError{Reason: "Invalid patch: " + err.Error()}
+	}
+
+	if err := checkKnownFields(original, patched); err != nil {
+		return models.Task{}, err
+	}
+
+	var result models.Task
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return models.Task{}, &customError.BadRequestError{Reason: "Patch produced an invalid task: " + err.Error()}
+	}
+
+	if result.ID != task.ID || result.OwnerID != task.OwnerID || result.Version != task.Version || result.DeletedAt != nil {
+		return models.Task{}, &customError.BadRequestError{Reason: "The id, owner_id and version fields can not be changed"}
+	}
+	if result.SeriesID != task.SeriesID || result.Occurrence != task.Occurrence {
+		return models.Task{}, &customError.BadRequestError{Reason: "The series_id and occurrence fields are set by the server"}
+	}
+	// compared in their JSON form, which leaves out the blob keys
+	oldAttachments, _ := json.Marshal(task.Attachments)
+	newAttachments, _ := json.Marshal(result.Attachments)
+	if !bytes.Equal(oldAttachments, newAttachments) {
+		return models.Task{}, &customError.BadRequestError{Reason: "Attachments can only be changed through the attachment endpoints"}
+	}
+	result.Attachments = task.Attachments
+
+	return result, nil
+}
+
+// checkKnownFields rejects patches that add fields the task does not have,
+// which usually means a misspelled field name.
+func checkKnownFields(original, patched []byte) error {
+	var known, result map[string]json.RawMessage
+	if err := json.Unmarshal(original, &known); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return &customError.BadRequestError{Reason: "Patch produced an invalid task: " + err.Error()}
+	}
+
+	for field := range result {
+		if _, ok := known[field]; !ok {
+			return &customError.BadRequestError{Reason: fmt.Sprintf("Unknown task field %q", field)}
+		}
+	}
+	return nil
+}
+
+// changedTaskFields returns the stored fields whose values differ between
+// the two tasks, ready to be used as a $set document.
+func changedTaskFields(oldTask, newTask models.Task) (bson.D, error) {
+	oldDoc, err := toBSONMap(oldTask)
+	if err != nil {
+		return nil, err
+	}
+	newDoc, err := toBSONMap(newTask)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := bson.D{}
+	for key, value := range newDoc {
+		if !reflect.DeepEqual(oldDoc[key], value) {
+			changed = append(changed, bson.E{Key: key, Value: value})
+		}
+	}
+	return changed, nil
+}
+
+func toBSONMap(task models.Task) (bson.M, error) {
+	raw, err := bson.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	err = bson.Unmarshal(raw, &doc)
+	return doc, err
+}
@@ -0,0 +1,141 @@
+package data
+
+import (
+	"context"
+	"task_manager/models"
+	"time"
+)
+
+// AllOwners is the owner scope of admins (see models.Actor.OwnerScope): it
+// skips the per-user ownership check.
+const AllOwners = 0
+
+// AnyVersion is passed as version to write regardless of the stored
+// version, i.e. when the client sent no If-Match header.
+const AnyVersion = 0
+
+// TaskRepository is the storage abstraction the controllers depend on.
+// Every backend must apply the same validation rules and return the same
+// customError types so handlers behave identically regardless of storage.
+//
+// Lookups are scoped to the actor; a task owned by someone else is reported
+// as not found rather than forbidden so its existence is not leaked. Writes
+// take the version the client last saw and fail with a
+// PreconditionFailedError if the task has moved on since.
+//
+// Every method takes the caller's context, so an abandoned request stops
+// its database work; the database backends also bound each call with the
+// deadlines set by SetTimeouts.
+type TaskRepository interface {
+	GetAllTasks(ctx context.Context, query models.TaskQuery) (models.TaskPage, error)
+	// EachTask calls fn with every task matching query, in the query's
+	// order but ignoring Offset and Limit. Tasks are streamed rather than
+	// collected, and the first error returned by fn stops the iteration.
+	EachTask(ctx context.Context, query models.TaskQuery, fn func(models.Task) error) error
+	// SearchTasks finds the tasks whose title or description match text,
+	// in MongoDB's $text syntax, among those matching query. Hits are
+	// ranked by relevance, so the query's ordering is not used.
+	SearchTasks(ctx context.Context, text string, query models.TaskQuery) (models.TaskSearchPage, error)
+	// GetTask returns a live task the actor may open: one they own, are
+	// assigned to or watch (see models.Actor.CanRead). Every other method
+	// taking an actor only reaches the tasks it owns.
+	GetTask(ctx context.Context, id string, actor models.Actor) (models.Task, error)
+	// UpdateTask replaces a task. Its attachments are kept, as they can
+	// only be changed with AddAttachment and RemoveAttachment, and so is
+	// its place in a recurring series.
+	UpdateTask(ctx context.Context, id string, actor models.Actor, version int, updatedTask models.Task) (models.Task, error)
+	// PatchTask applies a merge patch or JSON patch and persists only the
+	// fields it changed.
+	PatchTask(ctx context.Context, id string, actor models.Actor, version int, format PatchFormat, patch []byte) (models.Task, error)
+	// UpdateTags adds and removes tags of a task, leaving its other fields
+	// alone. Tags are normalized first and removing a missing tag is not
+	// an error.
+	UpdateTags(ctx context.Context, id string, actor models.Actor, version int, add, remove []string) (models.Task, error)
+	// TagCounts lists the tags of the tasks matching query with the number
+	// of tasks carrying each, most used first.
+	TagCounts(ctx context.Context, query models.TaskQuery) ([]models.TagCount, error)
+	// UpdateTaskUsers adds users to and removes users from one of the user
+	// lists of a task, leaving its other fields alone. Added users must
+	// exist; removing a user who is not on the list is not an error.
+	UpdateTaskUsers(ctx context.Context, id string, actor models.Actor, version int, list models.UserList, add, remove []int) (models.Task, error)
+	// AddAttachment lists a file, whose bytes are already in the blob
+	// store, on a task. The attachment gets its ID here and is returned as
+	// the last attachment of the task.
+	AddAttachment(ctx context.Context, id string, actor models.Actor, version int, attachment models.Attachment) (models.Task, error)
+	// RemoveAttachment takes an attachment off a task; deleting its blob is
+	// left to the caller.
+	RemoveAttachment(ctx context.Context, id string, actor models.Actor, version int, attachmentID int) (models.Task, error)
+	// AdvanceSeries continues the recurring series whose latest occurrence
+	// is head, if head is still at the same version and its rule allows
+	// another occurrence: the next occurrence is created and the rule
+	// moves on to it. An occurrence already stored by an earlier call that
+	// was cut short is kept rather than created twice, so calls can be
+	// repeated safely. Visibility is not checked; it is meant for the
+	// system rather than users.
+	AdvanceSeries(ctx context.Context, head models.Task) (SeriesAdvance, error)
+	// RemoveUser takes a deleted user off the user lists of every task,
+	// bumping the version of the tasks it changes, and returns the changes.
+	RemoveUser(ctx context.Context, userID int) ([]TaskChange, error)
+	// GetTaskGraph returns the tasks linked to a task as parent, subtask
+	// or blocker, directly or through other tasks.
+	GetTaskGraph(ctx context.Context, id string, actor models.Actor) (models.TaskGraph, error)
+	// DeleteTask moves a task to the trash; it is hidden from every other
+	// method until restored with RestoreDeletedTask or purged. Links to it
+	// are kept but ignored while it is in the trash (see checkLinks). Both
+	// moves bump the version, like any other write.
+	DeleteTask(ctx context.Context, id string, actor models.Actor, version int) error
+	RestoreDeletedTask(ctx context.Context, id string, actor models.Actor, version int) (models.Task, error)
+	// PurgeDeletedTasks permanently removes tasks trashed before the given
+	// time. Other tasks lose their links to them. Tasks restored while the
+	// purge runs are kept and not returned.
+	PurgeDeletedTasks(ctx context.Context, deletedBefore time.Time) (PurgeResult, error)
+	AddATask(ctx context.Context, task models.Task) (models.Task, error)
+	// BulkWrite runs a batch of creates, updates and deletes with the same
+	// rules as the single-task methods and returns one result per
+	// operation; a failed operation does not stop the others. The error is
+	// only set if the batch as a whole could not be run.
+	BulkWrite(ctx context.Context, actor models.Actor, ops []models.BulkOperation) ([]BulkResult, error)
+	// PutTask stores task under its own ID, creating it or replacing it
+	// (even from the trash), and reports what it replaced and whether
+	// anything was written: a task equal to the stored one is left alone.
+	// It is meant for loading fixtures and skips ownership checks and the
+	// status workflow.
+	PutTask(ctx context.Context, task models.Task) (PutResult, error)
+}
+
+// TaskChange is a write the system made to a task as a consequence of
+// another write, such as deleting a user or purging a task it linked to.
+type TaskChange struct {
+	Previous models.Task
+	Task     models.Task
+}
+
+// PurgeResult is the outcome of PurgeDeletedTasks.
+type PurgeResult struct {
+	Purged []models.Task
+	// Unlinked are the live or trashed tasks that lost links to the
+	// purged ones.
+	Unlinked []TaskChange
+}
+
+// PutResult is the outcome of PutTask.
+type PutResult struct {
+	// Previous is the task that was stored under the ID before, nil if
+	// there was none.
+	Previous *models.Task
+	Task     models.Task
+	// Written is false if the stored task already had the same values.
+	Written bool
+}
+
+// seedTask is inserted into an empty store on startup.
+func seedTask() models.Task {
+	return models.Task{
+		ID:          1,
+		Title:       "Learn Go",
+		Description: "Practice structs and interfaces",
+		DueDate:     time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC),
+		Status:      models.Pending,
+		Version:     1,
+	}
+}
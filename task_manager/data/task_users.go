@@ -0,0 +1,114 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"task_manager/customError"
+	"task_manager/models"
+)
+
+const (
+	MaxAssigneesPerTask = 20
+	MaxWatchersPerTask  = 100
+)
+
+// maxTaskUsers bounds the length of each user list of a task.
+var maxTaskUsers = map[models.UserList]int{
+	models.Assignees: MaxAssigneesPerTask,
+	models.Watchers:  MaxWatchersPerTask,
+}
+
+// taskUserErrors adds the problems with the user lists of task to invalid.
+// Whether the users exist is checked separately by checkTaskUsers.
+func taskUserErrors(task models.Task, invalid *customError.ValidationError) {
+	for _, list := range models.UserLists {
+		ids := *task.Users(list)
+		if len(ids) > maxTaskUsers[list] {
+			invalid.Add(string(list), fmt.Sprintf("can not hold more than %d users", maxTaskUsers[list]))
+		}
+		for _, id := range ids {
+			if id <= 0 {
+				invalid.Add(string(list), fmt.Sprintf("%d is not a user ID", id))
+			}
+		}
+	}
+}
+
+// checkTaskUsers makes sure the users added to the lists of task, about to
+// be written over oldTask (nil for new tasks), exist. Users already on a
+// list are not checked again, so a task can still be written after one of
+// them has been deleted.
+func checkTaskUsers(ctx context.Context, users UserRepository, oldTask *models.Task, task models.Task) error {
+	added := map[models.UserList][]int{}
+	var lookup []int
+	for _, list := range models.UserLists {
+		var old []int
+		if oldTask != nil {
+			old = *oldTask.Users(list)
+		}
+		for _, id := range *task.Users(list) {
+			if id > 0 && !slices.Contains(old, id) {
+				added[list] = append(added[list], id)
+				lookup = append(lookup, id)
+			}
+		}
+	}
+	if len(lookup) == 0 {
+		return nil
+	}
+
+	found, err := users.FindUsers(ctx, models.NormalizeIDs(lookup))
+	if err != nil {
+		return err
+	}
+	invalid := &customError.ValidationError{}
+	for _, list := range models.UserLists {
+		for _, id := range added[list] {
+			if _, ok := found[id]; !ok {
+				invalid.Add(string(list), fmt.Sprintf("user %d does not exist", id))
+			}
+		}
+	}
+	return invalid.OrNil()
+}
+
+// checkReferences runs the checks of a write that look at other stored
+// data: checkTaskUsers and checkLinks.
+func checkReferences(ctx context.Context, links taskLinks, users UserRepository, oldTask *models.Task, task models.Task) error {
+	if err := checkTaskUsers(ctx, users, oldTask, task); err != nil {
+		return err
+	}
+	return checkLinks(ctx, links, oldTask, task)
+}
+
+// updateTaskUsers returns task with the users in add added to list and
+// those in remove taken off it, and whether that changed anything. The
+// result still has to be validated.
+func updateTaskUsers(task models.Task, list models.UserList, add, remove []int) (models.Task, bool) {
+	ids := task.Users(list)
+	updated := models.NormalizeIDs(append(slices.Clone(*ids), add...))
+	updated = slices.DeleteFunc(updated, func(id int) bool { return slices.Contains(remove, id) })
+	if len(updated) == 0 {
+		updated = nil
+	}
+
+	changed := !slices.Equal(updated, *ids)
+	*ids = updated
+	return task, changed
+}
+
+// unassignUser takes userID off every user list of task and bumps its
+// version if that changed it.
+func unassignUser(task models.Task, userID int) (models.Task, bool) {
+	changed := false
+	for _, list := range models.UserLists {
+		var listChanged bool
+		task, listChanged = updateTaskUsers(task, list, nil, []int{userID})
+		changed = changed || listChanged
+	}
+	if changed {
+		task.Version++
+	}
+	return task, changed
+}
@@ -0,0 +1,48 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Timeouts bounds how long a single repository operation may take on top of
+// whatever deadline the caller's context already carries. Zero means no
+// extra bound.
+type Timeouts struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
+var DefaultTimeouts = Timeouts{Read: 5 * time.Second, Write: 10 * time.Second}
+
+var timeouts = DefaultTimeouts
+
+// SetTimeouts replaces the per-operation deadlines used by the database
+// backends. It is meant to be called once on startup.
+func SetTimeouts(t Timeouts) {
+	timeouts = t
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, timeouts.Read)
+}
+
+func writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, timeouts.Write)
+}
+
+// IsTimeout reports whether err was caused by an operation running past its
+// deadline.
+func IsTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err)
+}
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"context"
+	"net/mail"
+	"strings"
+	"task_manager/customError"
+	"task_manager/models"
+)
+
+type UserRepository interface {
+	// AddUser stores a new user with an already hashed password and returns
+	// it with its assigned ID. A taken username yields a ConflictError.
+	AddUser(ctx context.Context, user models.User) (models.User, error)
+	GetUser(ctx context.Context, id int) (models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (models.User, error)
+	// FindUsers returns the users with the given IDs that exist.
+	FindUsers(ctx context.Context, ids []int) (map[int]models.User, error)
+	// ListUsers returns a page of users ordered by ID.
+	ListUsers(ctx context.Context, offset, limit int) (models.UserPage, error)
+	// UpdateUser replaces the username, password hash, role and email of
+	// the user with user.ID. A taken username yields a ConflictError.
+	UpdateUser(ctx context.Context, user models.User) (models.User, error)
+	// DeleteUser removes a user. Their tasks are kept; taking them off the
+	// tasks they are assigned to or watch is left to
+	// TaskRepository.RemoveUser.
+	DeleteUser(ctx context.Context, id int) error
+}
+
+// normalizeUsername makes usernames case-insensitive and trims stray spaces.
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func validateUser(user models.User) error {
+	invalid := &customError.ValidationError{}
+	if len(user.Username) < 3 || len(user.Username) > 32 {
+		invalid.Add("username", "must be between 3 and 32 characters")
+	}
+	if user.PasswordHash == "" {
+		invalid.Add("password", "can not be empty")
+	}
+	if user.Role != models.RoleUser && user.Role != models.RoleAdmin {
+		invalid.Add("role", "must be either 'user' or 'admin'")
+	}
+	if user.Email != "" {
+		if address, err := mail.ParseAddress(user.Email); err != nil || address.Address != user.Email {
+			invalid.Add("email", "must be an address such as ann@example.com")
+		}
+	}
+	return invalid.OrNil()
+}
+
+func usernameTakenError(username string) error {
+	return &customError.ConflictError{Reason: "Username '" + username + "' is already taken"}
+}
+
+func userNotFoundError(id int) error {
+	return &customError.NotFoundError{Resource: "User", ID: id}
+}
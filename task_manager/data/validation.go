@@ -0,0 +1,191 @@
+package data
+
+import (
+	"fmt"
+	"strconv"
+	"task_manager/customError"
+	"task_manager/models"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxTagsPerTask = 20
+	MaxTagLength   = 32
+)
+
+const (
+	MaxRemindersPerTask = 10
+	// MaxReminderOffset bounds how many minutes before or after its due
+	// date a reminder can be sent: a year.
+	MaxReminderOffset = 366 * 24 * 60
+)
+
+func parseTaskID(id string) (int, error) {
+	taskID, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, &customError.BadRequestError{Reason: "Invalid format of ID!"}
+	}
+	return taskID, nil
+}
+
+func validateTask(task models.Task) error {
+	return taskFieldErrors(task).OrNil()
+}
+
+// taskFieldErrors collects every problem with task's fields so clients can
+// fix them all in one go.
+func taskFieldErrors(task models.Task) *customError.ValidationError {
+	invalid := &customError.ValidationError{}
+	if task.Title == "" {
+		invalid.Add("title", "can not be empty")
+	}
+	if task.Description == "" {
+		invalid.Add("description", "can not be empty")
+	}
+	if task.DueDate.IsZero() {
+		invalid.Add("due_date", "can not be empty")
+	}
+	if !workflow.IsKnown(task.Status) {
+		invalid.Add("status", unknownStatusReason())
+	}
+	if len(task.Tags) > MaxTagsPerTask {
+		invalid.Add("tags", fmt.Sprintf("can not hold more than %d tags", MaxTagsPerTask))
+	}
+	for _, tag := range task.Tags {
+		if reason := tagReason(tag); reason != "" {
+			invalid.Add("tags", fmt.Sprintf("%q %s", tag, reason))
+		}
+	}
+	if len(task.Reminders) > MaxRemindersPerTask {
+		invalid.Add("reminders", fmt.Sprintf("can not hold more than %d reminders", MaxRemindersPerTask))
+	}
+	for _, offset := range task.Reminders {
+		if offset < -MaxReminderOffset || offset > MaxReminderOffset {
+			invalid.Add("reminders", fmt.Sprintf("%d minutes is more than a year away from the due date", offset))
+		}
+	}
+	if task.Recurrence != "" {
+		if _, err := parseRecurrence(task.Recurrence); err != nil {
+			invalid.Add("recurrence", err.Error())
+		}
+	}
+	taskUserErrors(task, invalid)
+	return invalid
+}
+
+// tagReason explains why a normalized tag is invalid, or returns "".
+func tagReason(tag string) string {
+	if tag == "" {
+		return "can not be empty"
+	}
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return fmt.Sprintf("can not be longer than %d characters", MaxTagLength)
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.' {
+			return "may only contain letters, digits, '-', '_' and '.'"
+		}
+	}
+	return ""
+}
+
+// validateNewTask additionally requires a status tasks may start in.
+func validateNewTask(task models.Task) error {
+	invalid := taskFieldErrors(task)
+	if workflow.IsKnown(task.Status) && !workflow.CanStartIn(task.Status) {
+		invalid.Add("status", "new tasks must start as one of "+joinStatuses(workflow.Initial))
+	}
+	return invalid.OrNil()
+}
+
+// validateTaskUpdate checks a replacement or patched task against the
+// stored one, including the status transition.
+func validateTaskUpdate(oldTask, newTask models.Task) error {
+	if err := validateTask(newTask); err != nil {
+		return err
+	}
+	return checkTransition(oldTask, newTask)
+}
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 100
+)
+
+// sortableTaskFields maps the accepted sort keys to the stored field names.
+// models.Task has no bson tags, so the driver stores fields lowercased.
+var sortableTaskFields = map[string]string{
+	"id":       "id",
+	"title":    "title",
+	"due_date": "duedate",
+	"status":   "status",
+}
+
+// normalizeTaskQuery validates a listing query and fills in defaults so both
+// backends see the same, already-checked values.
+func normalizeTaskQuery(query models.TaskQuery) (models.TaskQuery, error) {
+	if query.Status != "" && !workflow.IsKnown(query.Status) {
+		return query, unknownStatusError()
+	}
+
+	if !query.DueAfter.IsZero() && !query.DueBefore.IsZero() && query.DueBefore.Before(query.DueAfter) {
+		return query, &customError.BadRequestError{Reason: "due_before can not be earlier than due_after"}
+	}
+
+	if query.SortBy == "" {
+		query.SortBy = "id"
+	}
+	if _, ok := sortableTaskFields[query.SortBy]; !ok {
+		return query, &customError.BadRequestError{Reason: "Tasks can only be sorted by id, title, due_date or status"}
+	}
+
+	if query.Offset < 0 {
+		return query, &customError.BadRequestError{Reason: "Offset can not be negative"}
+	}
+	if query.Limit < 0 || query.Limit > MaxPageLimit {
+		return query, &customError.BadRequestError{Reason: "Limit must be between 1 and 100"}
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultPageLimit
+	}
+
+	return query, nil
+}
+
+// normalizePage checks the paging of the listings that have no filters of
+// their own, like normalizeTaskQuery does for tasks.
+func normalizePage(offset, limit int) (int, int, error) {
+	if offset < 0 {
+		return 0, 0, &customError.BadRequestError{Reason: "Offset can not be negative"}
+	}
+	if limit < 0 || limit > MaxPageLimit {
+		return 0, 0, &customError.BadRequestError{Reason: "Limit must be between 1 and 100"}
+	}
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+	return offset, limit, nil
+}
+
+// checkVersion rejects a write whose If-Match version no longer matches
+// the stored task.
+func checkVersion(task models.Task, version int) error {
+	if version != AnyVersion && task.Version != version {
+		return staleVersionError(task.ID)
+	}
+	return nil
+}
+
+func staleVersionError(taskID int) error {
+	return &customError.PreconditionFailedError{Reason: fmt.Sprintf("Task with ID %d has been modified since it was fetched", taskID)}
+}
+
+// concurrentWriteError reports that the task changed between our read and
+// write. Conditional requests get 412; unconditional ones are asked to retry.
+func concurrentWriteError(taskID, version int) error {
+	if version != AnyVersion {
+		return staleVersionError(taskID)
+	}
+	return &customError.ConflictError{Reason: fmt.Sprintf("Task with ID %d was modified concurrently, please retry", taskID)}
+}
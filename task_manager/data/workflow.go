@@ -0,0 +1,75 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"task_manager/customError"
+	"task_manager/models"
+)
+
+// workflow is the status workflow every backend enforces. It is replaced at
+// most once, during startup, before any request is served.
+var workflow = models.DefaultWorkflow()
+
+// SetWorkflow replaces the default status workflow, and with it the
+// statuses models.Status.IsClosed reports.
+func SetWorkflow(w models.Workflow) error {
+	if err := w.Validate(); err != nil {
+		return fmt.Errorf("invalid workflow: %w", err)
+	}
+	workflow = w
+	models.ClosedStatuses = slices.Clone(w.Closed)
+	return nil
+}
+
+// LoadWorkflow reads a workflow definition from a JSON file of the form
+// {"initial": [...], "closed": [...], "transitions": {"Pending": ["InProgress", ...], ...}}.
+func LoadWorkflow(path string) (models.Workflow, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return models.Workflow{}, fmt.Errorf("failed to read workflow file: %w", err)
+	}
+
+	var w models.Workflow
+	if err := json.Unmarshal(raw, &w); err != nil {
+		return models.Workflow{}, fmt.Errorf("failed to parse workflow file: %w", err)
+	}
+	return w, nil
+}
+
+func unknownStatusReason() string {
+	return "must be one of " + joinStatuses(workflow.Statuses())
+}
+
+func unknownStatusError() error {
+	return &customError.ValidationError{Fields: []customError.FieldError{{Field: "status", Reason: unknownStatusReason()}}}
+}
+
+// checkTransition enforces the workflow when a write changes a task's status.
+func checkTransition(oldTask, newTask models.Task) error {
+	if !workflow.IsKnown(newTask.Status) {
+		return unknownStatusError()
+	}
+	if !workflow.CanTransition(oldTask.Status, newTask.Status) {
+		next := workflow.Next(oldTask.Status)
+		allowed := "none"
+		if len(next) > 0 {
+			allowed = joinStatuses(next)
+		}
+		return &customError.ConflictError{Reason: fmt.Sprintf(
+			"Task with ID %d can not move from %s to %s (allowed: %s)",
+			oldTask.ID, oldTask.Status, newTask.Status, allowed)}
+	}
+	return nil
+}
+
+func joinStatuses[S ~string](statuses []S) string {
+	names := make([]string, len(statuses))
+	for i, s := range statuses {
+		names[i] = "'" + string(s) + "'"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"task_manager/data"
+	"task_manager/models"
+	"time"
+)
+
+// maxOccurrencesPerRun bounds how many occurrences of one series a single
+// run creates, so a series that fell far behind catches up over several
+// runs rather than holding up the others.
+const maxOccurrencesPerRun = 50
+
+// RunRecurrenceScheduler creates the occurrences of recurring tasks that
+// fall due within horizon from now, as well as the next occurrence of any
+// series whose latest occurrence was closed without one, checking every
+// interval until ctx is cancelled. Occurrences are numbered within their
+// series and never stored twice, so restarts and several instances
+// running side by side do not duplicate them.
+func RunRecurrenceScheduler(ctx context.Context, tasks data.TaskRepository, horizon, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		created, err := scheduleOccurrences(ctx, tasks, time.Now().Add(horizon))
+		if err != nil {
+			log.Printf("Error scheduling recurring tasks: %v", err)
+		}
+		if created > 0 {
+			log.Printf("Created %d occurrence(s) of recurring tasks", created)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scheduleOccurrences advances every series whose next occurrence is due
+// by until, or whose latest occurrence is closed, and returns how many
+// occurrences it created.
+func scheduleOccurrences(ctx context.Context, tasks data.TaskRepository, until time.Time) (int, error) {
+	created := 0
+	err := tasks.EachTask(ctx, models.TaskQuery{OwnerID: data.AllOwners, Recurring: true}, func(head models.Task) error {
+		for i := 0; i < maxOccurrencesPerRun; i++ {
+			due, ok := data.NextOccurrence(head)
+			if !ok || (due.After(until) && !head.Status.IsClosed()) {
+				return nil
+			}
+			advance, err := tasks.AdvanceSeries(ctx, head)
+			if err != nil {
+				log.Printf("Error continuing the series of task %d: %v", head.ID, err)
+				return nil
+			}
+			if !advance.Created {
+				// the rule moved on to an occurrence stored earlier, which
+				// is looked at in a later run
+				return nil
+			}
+			created++
+			head = *advance.Next
+		}
+		return nil
+	})
+	return created, err
+}
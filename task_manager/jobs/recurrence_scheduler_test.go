@@ -0,0 +1,151 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"task_manager/data"
+	"task_manager/models"
+	"testing"
+	"time"
+)
+
+// newSeries stores the first task of a series, filling in what task lacks.
+func newSeries(t *testing.T, tasks *data.MemoryTaskRepository, task models.Task, due time.Time) models.Task {
+	t.Helper()
+	task.Title, task.Description = "Water the plants", "Every day"
+	task.DueDate, task.OwnerID = due.UTC().Truncate(time.Millisecond), trashOwner.UserID
+	if task.Status == "" {
+		task.Status = models.Pending
+	}
+	head, err := tasks.AddATask(context.Background(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return head
+}
+
+// occurrences returns the occurrence numbers stored for series, failing
+// the test if one is stored twice.
+func occurrences(t *testing.T, tasks data.TaskRepository, series int) []int {
+	t.Helper()
+	seen := map[int]bool{}
+	var numbers []int
+	err := tasks.EachTask(context.Background(), models.TaskQuery{OwnerID: data.AllOwners}, func(task models.Task) error {
+		if task.ID != series && task.SeriesID != series {
+			return nil
+		}
+		number := max(task.Occurrence, 1)
+		if seen[number] {
+			t.Errorf("occurrence %d of series %d is stored twice", number, series)
+		}
+		seen[number] = true
+		numbers = append(numbers, number)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return numbers
+}
+
+func TestScheduleOccurrencesIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	tasks := data.NewMemoryTaskRepository(false, data.NewMemoryUserRepository())
+	head := newSeries(t, tasks, models.Task{Recurrence: "FREQ=DAILY"}, time.Now().Add(time.Hour))
+	until := head.DueDate.Add(72*time.Hour + time.Minute)
+
+	for run, want := range []int{3, 0} {
+		created, err := scheduleOccurrences(ctx, tasks, until)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created != want {
+			t.Errorf("run %d created %d occurrences, want %d", run+1, created, want)
+		}
+	}
+	if got := occurrences(t, tasks, head.ID); len(got) != 4 {
+		t.Errorf("series has occurrences %v, want 1 to 4", got)
+	}
+}
+
+func TestScheduleOccurrencesSideBySide(t *testing.T) {
+	ctx := context.Background()
+	tasks := data.NewMemoryTaskRepository(false, data.NewMemoryUserRepository())
+	head := newSeries(t, tasks, models.Task{Recurrence: "FREQ=DAILY;COUNT=10"}, time.Now().Add(time.Hour))
+	until := head.DueDate.Add(30 * 24 * time.Hour)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := scheduleOccurrences(ctx, tasks, until); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	// a run that lost a race leaves the rest of the series to the next one
+	for {
+		created, err := scheduleOccurrences(ctx, tasks, until)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created == 0 {
+			break
+		}
+	}
+
+	if got := occurrences(t, tasks, head.ID); len(got) != 10 {
+		t.Errorf("series has occurrences %v, want 1 to 10", got)
+	}
+}
+
+func TestScheduleOccurrencesContinuesClosedSeries(t *testing.T) {
+	ctx := context.Background()
+	tasks := data.NewMemoryTaskRepository(false, data.NewMemoryUserRepository())
+	head := newSeries(t, tasks, models.Task{Recurrence: "FREQ=WEEKLY", Status: models.Completed}, time.Now().Add(30*24*time.Hour))
+
+	created, err := scheduleOccurrences(ctx, tasks, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 1 {
+		t.Errorf("created %d occurrences of a closed series, want 1", created)
+	}
+	if got := occurrences(t, tasks, head.ID); len(got) != 2 {
+		t.Errorf("series has occurrences %v, want 1 and 2", got)
+	}
+}
+
+func TestAdvanceSeriesStoresOccurrenceOnce(t *testing.T) {
+	ctx := context.Background()
+	tasks := data.NewMemoryTaskRepository(false, data.NewMemoryUserRepository())
+	head := newSeries(t, tasks, models.Task{Recurrence: "FREQ=DAILY"}, time.Now().Add(time.Hour))
+
+	first, err := tasks.AdvanceSeries(ctx, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Created {
+		t.Fatal("the first call did not create the next occurrence")
+	}
+
+	// as if the rule had not been moved off the head after the insert,
+	// for instance because the process stopped in between
+	put, err := tasks.PutTask(ctx, first.Previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head = put.Task
+	second, err := tasks.AdvanceSeries(ctx, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Created || second.Next == nil || second.Next.ID != first.Next.ID {
+		t.Errorf("the second call returned %+v, want the occurrence stored by the first", second)
+	}
+	if got := occurrences(t, tasks, head.ID); len(got) != 2 {
+		t.Errorf("series has occurrences %v, want 1 and 2", got)
+	}
+}
@@ -0,0 +1,229 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strconv"
+	"task_manager/customError"
+	"task_manager/data"
+	"task_manager/models"
+	"task_manager/notify"
+	"time"
+)
+
+const (
+	// deliveryLease is how long a claimed delivery is left to its
+	// dispatcher before another may try it again.
+	deliveryLease = 5 * time.Minute
+	// deliveriesPerClaim bounds how many deliveries are claimed at once.
+	deliveriesPerClaim = 100
+	// firstRetryDelay doubles with every failed attempt up to maxRetryDelay.
+	firstRetryDelay = 30 * time.Second
+	maxRetryDelay   = time.Hour
+)
+
+// ReminderDispatcher sends the reminders of tasks through Channels. Every
+// reminder gets one delivery record per channel before anything is sent,
+// and the record, not the scan that found the reminder, decides whether
+// it still has to go out, so restarts and several instances running side
+// by side send each reminder once.
+type ReminderDispatcher struct {
+	Tasks      data.TaskRepository
+	Users      data.UserRepository
+	Deliveries data.ReminderRepository
+	Channels   []notify.Channel
+	// MaxDelay is how late a reminder may still be found; older ones are
+	// never sent.
+	MaxDelay time.Duration
+	// MaxAttempts is how often a delivery is tried before it fails.
+	MaxAttempts int
+}
+
+// Run looks for due reminders and sends them every interval until ctx is
+// cancelled.
+func (d *ReminderDispatcher) Run(ctx context.Context, interval time.Duration) {
+	if len(d.Channels) == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now().UTC()
+		found, err := d.scan(ctx, now)
+		if err != nil {
+			log.Printf("Error looking for due reminders: %v", err)
+		}
+		if found > 0 {
+			log.Printf("Queued %d reminder delivery(ies)", found)
+		}
+		if err := d.dispatch(ctx, now); err != nil {
+			log.Printf("Error sending reminders: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan stores a delivery for every channel of the latest reminder of each
+// open task that fell due since MaxDelay before now, and returns how many
+// it stored. Earlier reminders that fell due in the same window, after
+// downtime or a due date moved closer, are outdated by it and not sent.
+func (d *ReminderDispatcher) scan(ctx context.Context, now time.Time) (int, error) {
+	since := now.Add(-d.MaxDelay)
+	reach := time.Duration(data.MaxReminderOffset) * time.Minute
+	query := models.TaskQuery{
+		OwnerID:      data.AllOwners,
+		HasReminders: true,
+		DueAfter:     since.Add(-reach),
+		DueBefore:    now.Add(reach),
+	}
+
+	found := 0
+	err := d.Tasks.EachTask(ctx, query, func(task models.Task) error {
+		if task.Status.IsClosed() {
+			return nil
+		}
+		offsets := task.RemindersBetween(since, now)
+		if len(offsets) == 0 {
+			return nil
+		}
+		// Reminders is sorted, so the smallest offset is the latest reminder
+		offset := offsets[0]
+		for _, channel := range d.Channels {
+			delivery := models.ReminderDelivery{
+				TaskID:        task.ID,
+				Offset:        offset,
+				DueDate:       task.DueDate,
+				Channel:       channel.Name(),
+				NextAttemptAt: now,
+			}
+			_, created, err := d.Deliveries.AddDelivery(ctx, delivery)
+			if err != nil {
+				log.Printf("Error storing reminder %d of task %d: %v", offset, task.ID, err)
+				continue
+			}
+			if created {
+				found++
+			}
+		}
+		return nil
+	})
+	return found, err
+}
+
+// dispatch sends the pending deliveries due by now, a batch at a time.
+func (d *ReminderDispatcher) dispatch(ctx context.Context, now time.Time) error {
+	for ctx.Err() == nil {
+		claimed, err := d.Deliveries.ClaimDeliveries(ctx, now, now.Add(deliveryLease), deliveriesPerClaim)
+		if err != nil {
+			return err
+		}
+		for _, delivery := range claimed {
+			d.deliver(ctx, delivery)
+		}
+		if len(claimed) < deliveriesPerClaim {
+			return nil
+		}
+	}
+	return nil
+}
+
+// deliver makes one attempt at a claimed delivery and records its outcome.
+func (d *ReminderDispatcher) deliver(ctx context.Context, delivery models.ReminderDelivery) {
+	reminder, skip, err := d.reminder(ctx, delivery)
+	if err == nil && skip == "" {
+		err = d.channel(delivery.Channel).Send(ctx, reminder)
+		if errors.Is(err, notify.ErrNoRecipients) {
+			skip, err = err.Error(), nil
+		}
+	}
+
+	now := time.Now().UTC()
+	switch {
+	case skip != "":
+		delivery.Status = models.DeliverySkipped
+		delivery.LastError = skip
+	case err == nil:
+		delivery.Status = models.DeliverySent
+		delivery.LastError = ""
+		delivery.SentAt = &now
+	case notify.IsPermanent(err) || delivery.Attempts >= d.MaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(retryDelay(delivery.Attempts))
+	}
+	if err != nil {
+		log.Printf("Error sending reminder %d of task %d through %s (attempt %d): %v", delivery.ID, delivery.TaskID, delivery.Channel, delivery.Attempts, err)
+	}
+
+	// the outcome must be stored even once ctx is cancelled, or a sent
+	// reminder would go out again when its lease runs out
+	if err := d.Deliveries.UpdateDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		log.Printf("Error recording reminder %d of task %d: %v", delivery.ID, delivery.TaskID, err)
+	}
+}
+
+// reminder loads what delivery is sent with. It returns why the delivery
+// is skipped instead if it is no longer relevant: the task is gone or
+// closed, or its due date or reminders changed since it was found.
+func (d *ReminderDispatcher) reminder(ctx context.Context, delivery models.ReminderDelivery) (notify.Reminder, string, error) {
+	if d.channel(delivery.Channel) == nil {
+		return notify.Reminder{}, "the channel is no longer enabled", nil
+	}
+
+	task, err := d.Tasks.GetTask(ctx, strconv.Itoa(delivery.TaskID), models.Actor{Admin: true})
+	var notFound *customError.NotFoundError
+	switch {
+	case errors.As(err, &notFound):
+		return notify.Reminder{}, "the task was deleted", nil
+	case err != nil:
+		return notify.Reminder{}, "", err
+	case task.Status.IsClosed():
+		return notify.Reminder{}, "the task was closed", nil
+	case !task.DueDate.Equal(delivery.DueDate) || !slices.Contains(task.Reminders, delivery.Offset):
+		return notify.Reminder{}, "the reminder was changed", nil
+	}
+
+	ids := append([]int{task.OwnerID}, task.Assignees...)
+	ids = append(ids, task.Watchers...)
+	users, err := d.Users.FindUsers(ctx, ids)
+	if err != nil {
+		return notify.Reminder{}, "", err
+	}
+	reminder := notify.Reminder{Delivery: delivery, Task: task}
+	for _, id := range ids {
+		if user, ok := users[id]; ok {
+			reminder.Recipients = append(reminder.Recipients, user)
+			delete(users, id)
+		}
+	}
+	return reminder, "", nil
+}
+
+// channel returns the enabled channel called name, or nil.
+func (d *ReminderDispatcher) channel(name string) notify.Channel {
+	for _, channel := range d.Channels {
+		if channel.Name() == name {
+			return channel
+		}
+	}
+	return nil
+}
+
+// retryDelay returns how long to wait after the given number of failed
+// attempts.
+func retryDelay(attempts int) time.Duration {
+	if attempts > 7 {
+		return maxRetryDelay
+	}
+	return min(firstRetryDelay<<(attempts-1), maxRetryDelay)
+}
@@ -0,0 +1,159 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"task_manager/data"
+	"task_manager/models"
+	"task_manager/notify"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// fakeChannel fails with the errors in errs, one per call, and succeeds
+// once they are used up.
+type fakeChannel struct {
+	errs  []error
+	calls int
+}
+
+func (c *fakeChannel) Name() string { return "fake" }
+
+func (c *fakeChannel) Send(ctx context.Context, reminder notify.Reminder) error {
+	c.calls++
+	if len(c.errs) == 0 {
+		return nil
+	}
+	err := c.errs[0]
+	c.errs = c.errs[1:]
+	return err
+}
+
+// newDispatcher returns a dispatcher with one delivery through channel
+// waiting to be sent.
+func newDispatcher(t *testing.T, channel *fakeChannel, maxAttempts int) (*ReminderDispatcher, *data.MemoryReminderRepository) {
+	t.Helper()
+	ctx := context.Background()
+	tasks := data.NewMemoryTaskRepository(false, data.NewMemoryUserRepository())
+	if _, err := tasks.AddATask(ctx, models.Task{
+		Title:       "Call the bank",
+		Description: "About the card",
+		DueDate:     time.Now().Add(30 * time.Minute).UTC().Truncate(time.Millisecond),
+		Status:      models.Pending,
+		OwnerID:     trashOwner.UserID,
+		Reminders:   []int{60},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries := data.NewMemoryReminderRepository()
+	d := &ReminderDispatcher{
+		Tasks:       tasks,
+		Users:       data.NewMemoryUserRepository(),
+		Deliveries:  deliveries,
+		Channels:    []notify.Channel{channel},
+		MaxDelay:    24 * time.Hour,
+		MaxAttempts: maxAttempts,
+	}
+	if found, err := d.scan(ctx, time.Now().UTC()); err != nil || found != 1 {
+		t.Fatalf("scan found %d reminders (%v), want 1", found, err)
+	}
+	return d, deliveries
+}
+
+// attempt dispatches the deliveries due after wait and returns the one
+// delivery as it was left.
+func attempt(t *testing.T, d *ReminderDispatcher, deliveries *data.MemoryReminderRepository, wait time.Duration) models.ReminderDelivery {
+	t.Helper()
+	if err := d.dispatch(context.Background(), time.Now().UTC().Add(wait)); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := deliveries.GetTaskDeliveries(context.Background(), 1)
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("got deliveries %v (%v), want one", stored, err)
+	}
+	return stored[0]
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	transient := errors.New("connection refused")
+	channel := &fakeChannel{errs: []error{transient, transient}}
+	d, deliveries := newDispatcher(t, channel, 3)
+
+	start := time.Now()
+	delivery := attempt(t, d, deliveries, 0)
+	if delivery.Status != models.DeliveryPending || delivery.Attempts != 1 || delivery.LastError != transient.Error() {
+		t.Fatalf("after a failed attempt got %+v", delivery)
+	}
+	if wait := delivery.NextAttemptAt.Sub(start); wait < firstRetryDelay || wait > firstRetryDelay+time.Minute {
+		t.Errorf("first retry in %s, want %s", wait, firstRetryDelay)
+	}
+
+	// not yet due again
+	attempt(t, d, deliveries, 0)
+	if channel.calls != 1 {
+		t.Fatalf("retried before the delay, %d calls", channel.calls)
+	}
+
+	delivery = attempt(t, d, deliveries, firstRetryDelay+time.Second)
+	if delivery.Status != models.DeliveryPending || delivery.Attempts != 2 {
+		t.Fatalf("after a second failed attempt got %+v", delivery)
+	}
+	if wait := delivery.NextAttemptAt.Sub(start); wait < 2*firstRetryDelay {
+		t.Errorf("second retry in %s, want at least %s", wait, 2*firstRetryDelay)
+	}
+
+	delivery = attempt(t, d, deliveries, 3*firstRetryDelay+time.Second)
+	if delivery.Status != models.DeliverySent || delivery.Attempts != 3 || delivery.SentAt == nil || delivery.LastError != "" {
+		t.Errorf("after a successful attempt got %+v", delivery)
+	}
+}
+
+func TestDeliverGivesUp(t *testing.T) {
+	transient := errors.New("connection refused")
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+	}{
+		{"after the last attempt", []error{transient, transient, transient}, 2},
+		{"on a permanent error", []error{notify.Permanent(errors.New("bad request"))}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel := &fakeChannel{errs: tt.errs}
+			d, deliveries := newDispatcher(t, channel, 2)
+
+			var delivery models.ReminderDelivery
+			for range tt.attempts {
+				delivery = attempt(t, d, deliveries, maxRetryDelay)
+			}
+			if delivery.Status != models.DeliveryFailed || delivery.Attempts != tt.attempts {
+				t.Errorf("got %+v, want failed after %d attempts", delivery, tt.attempts)
+			}
+			attempt(t, d, deliveries, 2*maxRetryDelay)
+			if channel.calls != tt.attempts {
+				t.Errorf("channel was called %d times, want %d", channel.calls, tt.attempts)
+			}
+		})
+	}
+}
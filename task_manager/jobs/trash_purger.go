@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"task_manager/data"
+	"time"
+)
+
+// RunTrashPurger permanently deletes tasks that have been in the trash for
+// longer than retention, together with their comments, reminder deliveries
+// and the blobs of their attachments, checking every interval until ctx is
+// cancelled.
+func RunTrashPurger(ctx context.Context, tasks data.TaskRepository, comments data.CommentRepository, reminders data.ReminderRepository, blobs data.BlobStore, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purgeTrash(ctx, tasks, comments, reminders, blobs, time.Now().Add(-retention))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeTrash purges the tasks trashed before deletedBefore and deletes
+// what belongs to them. It relies on the repository returning only the
+// tasks that are really gone, not those restored while the purge ran.
+func purgeTrash(ctx context.Context, tasks data.TaskRepository, comments data.CommentRepository, reminders data.ReminderRepository, blobs data.BlobStore, deletedBefore time.Time) {
+	result, err := tasks.PurgeDeletedTasks(ctx, deletedBefore)
+	if err != nil {
+		log.Printf("Error purging trash: %v", err)
+	}
+	purged := result.Purged
+	if len(purged) == 0 {
+		return
+	}
+	log.Printf("Purged %d task(s) from the trash", len(purged))
+
+	ids := make([]int, len(purged))
+	for i, task := range purged {
+		ids[i] = task.ID
+	}
+	if _, err := comments.DeleteTaskComments(ctx, ids); err != nil {
+		log.Printf("Error deleting comments of purged tasks %v: %v", ids, err)
+	}
+	if _, err := reminders.DeleteTaskDeliveries(ctx, ids); err != nil {
+		log.Printf("Error deleting reminders of purged tasks %v: %v", ids, err)
+	}
+	for _, task := range purged {
+		for _, attachment := range task.Attachments {
+			if err := blobs.Delete(ctx, attachment.BlobKey); err != nil {
+				log.Printf("Error deleting attachment %d of purged task %d: %v", attachment.ID, task.ID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"task_manager/data"
+	"task_manager/models"
+	"testing"
+	"time"
+)
+
+// trashFixture is a memory store with trashed tasks that each have a
+// comment, an attachment and a reminder delivery.
+type trashFixture struct {
+	tasks     *data.MemoryTaskRepository
+	comments  *data.MemoryCommentRepository
+	reminders *data.MemoryReminderRepository
+	blobs     *data.FilesystemBlobStore
+	attached  map[int]string // blob key by task ID
+}
+
+var trashOwner = models.Actor{UserID: 1}
+
+func newTrashFixture(t *testing.T, count int) *trashFixture {
+	t.Helper()
+	ctx := context.Background()
+
+	blobs, err := data.NewFilesystemBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &trashFixture{
+		tasks:     data.NewMemoryTaskRepository(false, data.NewMemoryUserRepository()),
+		comments:  data.NewMemoryCommentRepository(),
+		reminders: data.NewMemoryReminderRepository(),
+		blobs:     blobs,
+		attached:  map[int]string{},
+	}
+
+	for i := 0; i < count; i++ {
+		task, err := f.tasks.AddATask(ctx, models.Task{
+			Title:       "Task " + strconv.Itoa(i+1),
+			Description: "To purge",
+			DueDate:     time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond),
+			Status:      models.Pending,
+			OwnerID:     trashOwner.UserID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := strconv.Itoa(task.ID)
+
+		key := data.NewBlobKey()
+		if _, err := blobs.Put(ctx, key, strings.NewReader("bytes of "+id)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.tasks.AddAttachment(ctx, id, trashOwner, 0, models.Attachment{Name: "a.txt", BlobKey: key}); err != nil {
+			t.Fatal(err)
+		}
+		f.attached[task.ID] = key
+		if _, err := f.comments.AddComment(ctx, models.Comment{TaskID: task.ID, AuthorID: trashOwner.UserID, Body: "A comment"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := f.reminders.AddDelivery(ctx, models.ReminderDelivery{TaskID: task.ID, Offset: 5, DueDate: task.DueDate, Channel: "log"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.tasks.DeleteTask(ctx, id, trashOwner, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+// kept reports whether the task, its comment, its attachment blob
+// and its reminder delivery still exist, and fails if they disagree.
+func (f *trashFixture) kept(t *testing.T, taskID int) bool {
+	t.Helper()
+	ctx := context.Background()
+
+	comments, err := f.comments.GetComments(ctx, taskID, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err := f.reminders.GetTaskDeliveries(ctx, taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, blobErr := f.blobs.Open(ctx, f.attached[taskID])
+	if blobErr == nil {
+		blob.Close()
+	}
+	_, taskErr := f.tasks.GetTask(ctx, strconv.Itoa(taskID), trashOwner)
+
+	parts := map[string]bool{
+		"task":       taskErr == nil,
+		"comment":    comments.Total == 1,
+		"delivery":   len(deliveries) == 1,
+		"attachment": blobErr == nil,
+	}
+	for name, ok := range parts {
+		if ok != parts["task"] {
+			t.Errorf("task %d: task kept %v but %s kept %v", taskID, parts["task"], name, ok)
+		}
+	}
+	return parts["task"]
+}
+
+func TestPurgeTrashDeletesWhatBelongsToPurgedTasks(t *testing.T) {
+	f := newTrashFixture(t, 2)
+
+	purgeTrash(context.Background(), f.tasks, f.comments, f.reminders, f.blobs, time.Now().Add(time.Minute))
+
+	for taskID := range f.attached {
+		if f.kept(t, taskID) {
+			t.Errorf("task %d survived the purge", taskID)
+		}
+	}
+}
+
+// restoringTasks restores a task when the purge starts, as another
+// request may do while a MongoDB purge runs.
+type restoringTasks struct {
+	*data.MemoryTaskRepository
+	restore string
+}
+
+func (r restoringTasks) PurgeDeletedTasks(ctx context.Context, deletedBefore time.Time) (data.PurgeResult, error) {
+	if _, err := r.RestoreDeletedTask(ctx, r.restore, trashOwner, data.AnyVersion); err != nil {
+		return data.PurgeResult{}, err
+	}
+	return r.MemoryTaskRepository.PurgeDeletedTasks(ctx, deletedBefore)
+}
+
+func TestPurgeTrashKeepsTasksRestoredMidPurge(t *testing.T) {
+	f := newTrashFixture(t, 2)
+	tasks := restoringTasks{MemoryTaskRepository: f.tasks, restore: "1"}
+
+	purgeTrash(context.Background(), tasks, f.comments, f.reminders, f.blobs, time.Now().Add(time.Minute))
+
+	if !f.kept(t, 1) {
+		t.Error("task 1 was restored during the purge but is gone")
+	}
+	if f.kept(t, 2) {
+		t.Error("task 2 survived the purge")
+	}
+}
@@ -1,16 +1,186 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"task_manager/auth"
+	"task_manager/config"
+	task_controllers "task_manager/controllers"
+	"task_manager/customError"
 	"task_manager/data"
+	"task_manager/jobs"
+	"task_manager/models"
+	"task_manager/notify"
 	"task_manager/router"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
 )
 
 func main(){
-	err := data.InitMongo()
+	// a .env file is optional; its values act as environment variables
+	if err := godotenv.Load(); err != nil{
+		log.Println("No .env file loaded: ", err)
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil{
+		log.Println(err)
+		os.Exit(2)
+	}
+	if err := run(cfg); err != nil{
+		log.Println(err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg config.Config) error{
+	configureLogging(cfg.LogLevel)
+
+	tokens, err := auth.NewTokenService(cfg.Auth.JWTSecret, cfg.Auth.JWTTTL)
+	if err != nil{
+		return err
+	}
+
+	if cfg.WorkflowFile != ""{
+		workflow, err := data.LoadWorkflow(cfg.WorkflowFile)
+		if err == nil{
+			err = data.SetWorkflow(workflow)
+		}
+		if err != nil{
+			return err
+		}
+	}
+
+	data.SetTimeouts(data.Timeouts{Read: cfg.Timeouts.DBRead, Write: cfg.Timeouts.DBWrite})
+
+	// cancelled on SIGINT or SIGTERM, which starts the shutdown
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	store, err := data.NewStore(ctx, cfg.Storage, cfg.Attachments)
+	if err != nil{
+		return err
+	}
+	defer store.Close()
+
+	if err := ensureAdmin(ctx, store.Users, cfg.Auth.AdminUsername, cfg.Auth.AdminPassword); err != nil{
+		return err
+	}
+
+	if cfg.Storage.FixturesFile != ""{
+		changed, err := data.LoadFixtures(ctx, store.Tasks, cfg.Storage.FixturesFile)
+		if err != nil{
+			return err
+		}
+		log.Printf("Loaded fixtures from %s, %d task(s) created or updated", cfg.Storage.FixturesFile, changed)
+	}
+
+	// the jobs stop when ctx is cancelled; waiting for them before the
+	// deferred store.Close keeps a job from writing to a closed database
+	var workers sync.WaitGroup
+	defer func(){
+		cancel()
+		workers.Wait()
+	}()
+	start := func(job func()){
+		workers.Add(1)
+		go func(){
+			defer workers.Done()
+			job()
+		}()
+	}
+
+	start(func(){
+		jobs.RunTrashPurger(ctx, store.Tasks, store.Comments, store.Reminders, store.Blobs, cfg.Trash.Retention, cfg.Trash.PurgeInterval)
+	})
+	start(func(){ jobs.RunRecurrenceScheduler(ctx, store.Tasks, cfg.Recurrence.Horizon, cfg.Recurrence.Interval) })
+
+	channels, err := notify.NewChannels(cfg.Reminders)
 	if err != nil{
-		return 
+		return err
+	}
+	dispatcher := &jobs.ReminderDispatcher{
+		Tasks:       store.Tasks,
+		Users:       store.Users,
+		Deliveries:  store.Reminders,
+		Channels:    channels,
+		MaxDelay:    cfg.Reminders.MaxDelay,
+		MaxAttempts: cfg.Reminders.MaxAttempts,
+	}
+	start(func(){ dispatcher.Run(ctx, cfg.Reminders.Interval) })
+
+	r := router.InitRouter(
+		task_controllers.NewTaskController(store.Tasks),
+		task_controllers.NewUserController(store.Users, store.Tasks, tokens),
+		task_controllers.NewCommentController(store.Tasks, store.Comments),
+		task_controllers.NewAttachmentController(store.Tasks, store.Blobs, cfg.Attachments),
+		task_controllers.NewReminderController(store.Tasks, store.Reminders),
+		task_controllers.NewHealthController(ctx, store),
+		tokens,
+		store.Users,
+	)
+	server := &http.Server{Addr: cfg.Addr, Handler: r}
+
+	serveErr := make(chan error, 1)
+	go func(){
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed){
+			serveErr <- err
+			cancel()
+		}
+	}()
+
+	<-ctx.Done()
+	select{
+	case err := <-serveErr:
+		return err
+	default:
+	}
+	log.Println("Shutting down, waiting for in-flight requests")
+
+	// the deferred calls then wait for the jobs and disconnect the database
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.Timeouts.Shutdown)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil{
+		log.Println("Forced shutdown:", err)
+	}
+	return nil
+}
+
+// configureLogging maps the log level onto Gin: debug enables Gin's debug
+// output, warn and above also silence the per-request log lines.
+func configureLogging(level slog.Level){
+	if level > slog.LevelDebug{
+		gin.SetMode(gin.ReleaseMode)
+	}
+	if level > slog.LevelInfo{
+		gin.DefaultWriter = io.Discard
+	}
+}
+
+// ensureAdmin creates the admin account if both username and password are
+// set and the account does not exist yet.
+func ensureAdmin(ctx context.Context, users data.UserRepository, username, password string) error{
+	if username == "" || password == ""{
+		return nil
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil{
+		return err
+	}
+
+	_, err = users.AddUser(ctx, models.User{Username: username, PasswordHash: hash, Role: models.RoleAdmin})
+	if _, exists := err.(*customError.ConflictError); exists{
+		return nil
 	}
-	defer data.CloseMongo()
-	r := router.InitRouter()
-	r.Run("localhost:3000")
+	return err
 }
\ No newline at end of file
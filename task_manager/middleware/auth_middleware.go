@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"task_manager/auth"
+	"task_manager/customError"
+	"task_manager/data"
+
+	"github.com/gin-gonic/gin"
+)
+
+const claimsKey = "claims"
+
+// RequireAuth rejects requests without a valid "Authorization: Bearer"
+// token and stores the token's claims on the context for the handlers.
+// The user is looked up on every request, so tokens of deleted users stop
+// working at once and the username and role in the claims are the current
+// ones rather than those at login.
+func RequireAuth(tokens *auth.TokenService, users data.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token, found := strings.CutPrefix(header, "Bearer ")
+		if !found || token == "" {
+			AbortWithProblem(c, Problem{Status: http.StatusUnauthorized, Detail: "Missing bearer token"})
+			return
+		}
+
+		claims, err := tokens.Parse(token)
+		if err != nil {
+			AbortWithProblem(c, Problem{Status: http.StatusUnauthorized, Detail: err.Error()})
+			return
+		}
+
+		user, err := users.GetUser(c.Request.Context(), claims.UserID)
+		var notFound *customError.NotFoundError
+		if errors.As(err, &notFound) {
+			AbortWithProblem(c, Problem{Status: http.StatusUnauthorized, Detail: "The account of this token no longer exists"})
+			return
+		}
+		if err != nil {
+			log.Printf("Error looking up user %d on %s %s: %v", claims.UserID, c.Request.Method, c.Request.URL.Path, err)
+			AbortWithProblem(c, Problem{Status: http.StatusServiceUnavailable, Detail: "The database is unavailable, please retry"})
+			return
+		}
+		claims.Username, claims.Role = user.Username, user.Role
+
+		c.Set(claimsKey, claims)
+		c.Next()
+	}
+}
+
+// CurrentClaims returns the claims stored by RequireAuth. It must only be
+// called from handlers mounted behind that middleware.
+func CurrentClaims(c *gin.Context) *auth.Claims {
+	return c.MustGet(claimsKey).(*auth.Claims)
+}
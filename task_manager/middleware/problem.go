@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"task_manager/customError"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Problem is an RFC 7807 problem details body. Every error response of the
+// API uses it so clients can handle failures in one place.
+type Problem struct {
+	Type     string                   `json:"type"`
+	Title    string                   `json:"title"`
+	Status   int                      `json:"status"`
+	Detail   string                   `json:"detail,omitempty"`
+	Instance string                   `json:"instance,omitempty"`
+	Errors   []customError.FieldError `json:"errors,omitempty"`
+}
+
+// AbortWithProblem ends the request with problem as an
+// application/problem+json response.
+func AbortWithProblem(c *gin.Context, problem Problem) {
+	problem = problem.WithDefaults(c)
+	c.Header("Content-Type", "application/problem+json")
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// WithDefaults fills in Type, Title and Instance with "about:blank", the
+// status text and the request path if they are not set.
+func (problem Problem) WithDefaults(c *gin.Context) Problem {
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	if problem.Instance == "" {
+		problem.Instance = c.Request.URL.Path
+	}
+	return problem
+}
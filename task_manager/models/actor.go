@@ -0,0 +1,27 @@
+package models
+
+import "slices"
+
+// Actor is the authenticated user on whose behalf a repository call is
+// made. It decides which tasks are visible and is recorded in the history.
+type Actor struct {
+	UserID int
+	Admin  bool
+}
+
+// OwnerScope returns the owner a task must have to be visible to the
+// actor, or 0 for admins, who see every task.
+func (a Actor) OwnerScope() int {
+	if a.Admin {
+		return 0
+	}
+	return a.UserID
+}
+
+// CanRead reports whether the actor may open task: admins can open every
+// task, others the tasks they own, are assigned to or watch. Changing a
+// task still takes owning it (see OwnerScope).
+func (a Actor) CanRead(task Task) bool {
+	return a.Admin || task.OwnerID == a.UserID ||
+		slices.Contains(task.Assignees, a.UserID) || slices.Contains(task.Watchers, a.UserID)
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Attachment describes a file attached to a task. The metadata is stored
+// with the task, the bytes in a separate blob store under BlobKey.
+type Attachment struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	Checksum    string    `json:"checksum"` // hex encoded SHA-256 of the bytes
+	UploadedBy  int       `json:"uploaded_by"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+	// BlobKey names the bytes in the blob store; clients never see it.
+	BlobKey string `json:"-"`
+}
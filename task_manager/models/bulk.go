@@ -0,0 +1,19 @@
+package models
+
+type BulkOp string
+
+const (
+	BulkCreate BulkOp = "create"
+	BulkUpdate BulkOp = "update"
+	BulkDelete BulkOp = "delete"
+)
+
+// BulkOperation is one item of a bulk request. Creates need Task, updates
+// need ID and Task (a full replacement, like PUT) and deletes need ID.
+// Version plays the role of If-Match and is optional.
+type BulkOperation struct {
+	Op      BulkOp `json:"op"`
+	ID      int    `json:"id,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Task    *Task  `json:"task,omitempty"`
+}
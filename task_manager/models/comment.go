@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Comment is a message in the discussion of a task.
+type Comment struct {
+	ID        int        `json:"id"`
+	TaskID    int        `json:"task_id"`
+	AuthorID  int        `json:"author_id"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"` // set once the body has been changed
+	Version   int        `json:"version"` // incremented on every edit, exposed as the ETag
+	// Edits holds the earlier bodies, oldest first. Listings leave it out.
+	Edits []CommentEdit `json:"edits,omitempty"`
+}
+
+// CommentEdit is a body a comment had before an edit replaced it.
+type CommentEdit struct {
+	Body       string    `json:"body"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// CommentPage is a single page of the comments of a task, oldest first,
+// together with the total number of comments.
+type CommentPage struct {
+	Comments []Comment `json:"comments"`
+	Total    int64     `json:"total"`
+	Offset   int       `json:"offset"`
+	Limit    int       `json:"limit"`
+	Next     string    `json:"next,omitempty"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending DeliveryStatus = "pending" // waiting for its first or next attempt
+	DeliverySent    DeliveryStatus = "sent"
+	DeliveryFailed  DeliveryStatus = "failed"  // gave up after the last attempt
+	DeliverySkipped DeliveryStatus = "skipped" // no longer relevant when its turn came
+)
+
+// ReminderDelivery records one reminder of a task going out through one
+// channel. A reminder is identified by its task, offset and the due date
+// it was computed from, so moving the due date brings new reminders.
+type ReminderDelivery struct {
+	ID            int            `json:"id"`
+	TaskID        int            `json:"task_id"`
+	Offset        int            `json:"offset"` // minutes before the due date, as in Task.Reminders
+	DueDate       time.Time      `json:"due_date"`
+	Channel       string         `json:"channel"`
+	Status        DeliveryStatus `json:"status"`
+	Attempts      int            `json:"attempts"`
+	NextAttemptAt time.Time      `json:"next_attempt_at"` // when a pending delivery is tried next
+	LastError     string         `json:"last_error,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	SentAt        *time.Time     `json:"sent_at,omitempty"`
+}
+
+// RemindAt returns when the reminder is due to go out.
+func (d ReminderDelivery) RemindAt() time.Time {
+	return d.DueDate.Add(-time.Duration(d.Offset) * time.Minute)
+}
+
+// RemindersBetween returns the reminder offsets of the task whose time
+// falls after from and no later than to.
+func (t Task) RemindersBetween(from, to time.Time) []int {
+	var offsets []int
+	for _, offset := range t.Reminders {
+		at := t.DueDate.Add(-time.Duration(offset) * time.Minute)
+		if at.After(from) && !at.After(to) {
+			offsets = append(offsets, offset)
+		}
+	}
+	return offsets
+}
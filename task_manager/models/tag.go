@@ -0,0 +1,33 @@
+package models
+
+import (
+	"slices"
+	"strings"
+)
+
+// TagCount is a tag together with the number of tasks carrying it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// NormalizeTag lowercases a tag, trims it and joins its words with "-", so
+// "Needs Review" and "needs-review" are the same tag. It does not validate
+// the result; the data layer rejects invalid tags.
+func NormalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+}
+
+// NormalizeTags normalizes every tag and returns them sorted without
+// duplicates, or nil if there are none.
+func NormalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	normalized := make([]string, len(tags))
+	for i, tag := range tags {
+		normalized[i] = NormalizeTag(tag)
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
@@ -1,16 +1,152 @@
 package models
 
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
 type status string
 
+// Status converts a raw string into the task status type. It does not
+// validate the value; the data layer rejects unknown statuses.
+func Status(s string) status {
+	return status(s)
+}
+
 const (
-	Pending   status = "Pending"
-	Completed status = "Completed"
+	Pending    status = "Pending"
+	InProgress status = "InProgress"
+	Blocked    status = "Blocked"
+	Completed  status = "Completed"
+	Archived   status = "Archived"
 )
 
+// ClosedStatuses are the statuses in which a task no longer needs work, the
+// Closed statuses of the workflow in use; data.SetWorkflow keeps them in
+// step with it.
+var ClosedStatuses = DefaultWorkflow().Closed
+
+func (s status) IsClosed() bool {
+	return slices.Contains(ClosedStatuses, s)
+}
+
 type Task struct {
-	ID          int    `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	DueDate     string `json:"due_date"`
-	Status      status `json:"status"` // e.g., "pending", "completed"
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	DueDate     time.Time `json:"due_date"`
+	Status      status    `json:"status"` // e.g., "Pending", "InProgress", "Completed"
+	OwnerID     int       `json:"owner_id"`
+	Tags        []string  `json:"tags"` // normalized, sorted and unique; nil when there are none
+	ParentID    int       `json:"parent_id"` // the task this is a subtask of, 0 for none
+	BlockedBy   []int     `json:"blocked_by"` // tasks that must be closed before this one can be completed; sorted and unique
+	Assignees   []int     `json:"assignees"` // IDs of the users working on the task; sorted and unique
+	Watchers    []int     `json:"watchers"` // IDs of the users following the task; sorted and unique
+	Reminders   []int     `json:"reminders"` // minutes before the due date to send reminders at, negative for after it; sorted and unique
+	Attachments []Attachment `json:"attachments"` // managed through the attachment endpoints only; oldest first
+	Recurrence  string    `json:"recurrence"` // RFC 5545 RRULE such as FREQ=WEEKLY;BYDAY=MO; only the latest occurrence of a series carries it
+	SeriesID    int       `json:"series_id"` // the task a generated occurrence continues the series of, 0 for tasks not generated
+	Occurrence  int       `json:"occurrence"` // number of a generated occurrence in its series, the first task being 1; 0 for tasks not generated
+	Version     int       `json:"version"` // incremented on every write, exposed as the ETag
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"` // set while the task is in the trash
+}
+
+// IsOverdue reports whether the task is past its due date while still open.
+func (t Task) IsOverdue(now time.Time) bool {
+	return !t.Status.IsClosed() && !t.DueDate.IsZero() && t.DueDate.Before(now)
+}
+
+// taskJSON has the same fields as Task but none of its methods, so the
+// custom (un)marshalers below can delegate to encoding/json without recursing.
+type taskJSON Task
+
+// MarshalJSON adds the computed "overdue" field to the task and always
+// writes tags, blockers, assignees, watchers, reminders and attachments as
+// lists.
+func (t Task) MarshalJSON() ([]byte, error) {
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	if t.BlockedBy == nil {
+		t.BlockedBy = []int{}
+	}
+	if t.Assignees == nil {
+		t.Assignees = []int{}
+	}
+	if t.Watchers == nil {
+		t.Watchers = []int{}
+	}
+	if t.Reminders == nil {
+		t.Reminders = []int{}
+	}
+	if t.Attachments == nil {
+		t.Attachments = []Attachment{}
+	}
+	return json.Marshal(struct {
+		taskJSON
+		Overdue bool `json:"overdue"`
+	}{taskJSON(t), t.IsOverdue(time.Now())})
+}
+
+// UnmarshalJSON accepts due_date either as an RFC 3339 timestamp or as a
+// YYYY-MM-DD date and normalizes the tags, ID lists and reminders. The computed
+// "overdue" field is accepted and ignored so clients can send back a task
+// exactly as they received it.
+func (t *Task) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		taskJSON
+		DueDate *string `json:"due_date"`
+		Overdue *bool   `json:"overdue"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	task := Task(raw.taskJSON)
+	task.Tags = NormalizeTags(task.Tags)
+	task.BlockedBy = NormalizeIDs(task.BlockedBy)
+	task.Assignees = NormalizeIDs(task.Assignees)
+	task.Watchers = NormalizeIDs(task.Watchers)
+	task.Reminders = NormalizeIDs(task.Reminders)
+	if len(task.Attachments) == 0 {
+		task.Attachments = nil
+	}
+	task.DueDate = time.Time{}
+	if raw.DueDate != nil && *raw.DueDate != "" {
+		dueDate, _, err := ParseDueDate(*raw.DueDate)
+		if err != nil {
+			return err
+		}
+		task.DueDate = dueDate
+	}
+
+	*t = task
+	return nil
+}
+
+// NormalizeIDs sorts a list of IDs and drops duplicates. An empty list
+// becomes nil, which is how tasks store it.
+func NormalizeIDs(ids []int) []int {
+	if len(ids) == 0 {
+		return nil
+	}
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// ParseDueDate parses an RFC 3339 timestamp or a YYYY-MM-DD date (taken as
+// midnight UTC) and reports which form was used. Results are in UTC and
+// truncated to milliseconds, the precision MongoDB stores.
+func ParseDueDate(value string) (time.Time, bool, error) {
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date, true, nil
+	}
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("due_date %q must be an RFC 3339 timestamp or a YYYY-MM-DD date", value)
+	}
+	return timestamp.UTC().Truncate(time.Millisecond), false, nil
 }
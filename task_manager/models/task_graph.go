@@ -0,0 +1,26 @@
+package models
+
+type LinkType string
+
+const (
+	// LinkSubtask goes from a task to one of its subtasks.
+	LinkSubtask LinkType = "subtask"
+	// LinkBlocks goes from a blocker to the task it blocks.
+	LinkBlocks LinkType = "blocks"
+)
+
+type TaskLink struct {
+	From int      `json:"from"`
+	To   int      `json:"to"`
+	Type LinkType `json:"type"`
+}
+
+// TaskGraph is every task connected to a task through subtasks and
+// blockers, in either direction, with the links between them.
+type TaskGraph struct {
+	TaskID int        `json:"task_id"`
+	Tasks  []Task     `json:"tasks"`
+	Links  []TaskLink `json:"links"`
+	// Truncated is set if the graph was cut off at its size limit.
+	Truncated bool `json:"truncated,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type HistoryAction string
+
+const (
+	ActionCreate  HistoryAction = "create"
+	ActionUpdate  HistoryAction = "update"
+	ActionDelete  HistoryAction = "delete"
+	ActionRestore HistoryAction = "restore"
+	// ActionUndelete takes a task out of the trash; ActionPurge removes it for good.
+	ActionUndelete HistoryAction = "undelete"
+	ActionPurge    HistoryAction = "purge"
+)
+
+// FieldChange is one changed field of a task, named as in the API.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// TaskHistoryEntry is the audit record of a single write to a task.
+type TaskHistoryEntry struct {
+	TaskID    int           `json:"task_id"`
+	Action    HistoryAction `json:"action"`
+	ActorID   int           `json:"actor_id"`
+	Timestamp time.Time     `json:"timestamp"`
+	Version   int           `json:"version"` // version the task had after the change
+	Old       *Task         `json:"old,omitempty"`
+	New       *Task         `json:"new,omitempty"`
+	Changes   []FieldChange `json:"changes"`
+	// RestoredFrom is the version a restore copied its values from.
+	RestoredFrom int `json:"restored_from,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// TaskQuery describes the filters, ordering and page requested by GET /tasks.
+// Empty fields mean "no constraint".
+type TaskQuery struct {
+	Status    status
+	DueAfter  time.Time // inclusive
+	DueBefore time.Time // inclusive
+	Overdue   bool      // only open tasks past their due date
+	Title     string // case-insensitive substring match
+	AllTags   []string // tasks must carry every one of these tags
+	AnyTags   []string // tasks must carry at least one of these tags
+	ParentID  int      // only subtasks of this task
+	AssigneeID int     // only tasks assigned to this user
+	WatcherID  int     // only tasks watched by this user
+	Recurring  bool    // only tasks carrying a recurrence rule
+	HasReminders bool  // only tasks with at least one reminder
+	SortBy    string
+	SortDesc  bool
+	Offset    int
+	Limit     int
+	OwnerID   int // 0 lists every owner's tasks
+	Trash     bool // list soft-deleted tasks instead of live ones
+}
+
+// TaskPage is a single page of a task listing together with the total
+// number of tasks matching the query.
+type TaskPage struct {
+	Tasks  []*Task `json:"tasks"`
+	Total  int64   `json:"total"`
+	Offset int     `json:"offset"`
+	Limit  int     `json:"limit"`
+	Next   string  `json:"next,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// TaskSearchHit is a task found by a full-text search together with its
+// relevance and the matching parts of its text.
+type TaskSearchHit struct {
+	Task  *Task   `json:"task"`
+	Score float64 `json:"score"`
+	// Highlights maps "title" and "description", if they matched, to an
+	// HTML-escaped snippet with the matched words wrapped in <mark>.
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// TaskSearchPage is a single page of search hits, best match first,
+// together with the total number of matching tasks.
+type TaskSearchPage struct {
+	Hits   []TaskSearchHit `json:"hits"`
+	Total  int64           `json:"total"`
+	Offset int             `json:"offset"`
+	Limit  int             `json:"limit"`
+	Next   string          `json:"next,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// UserList names one of the lists of users kept on a task.
+type UserList string
+
+const (
+	Assignees UserList = "assignees"
+	Watchers  UserList = "watchers"
+)
+
+// UserLists are every list of users a task has.
+var UserLists = []UserList{Assignees, Watchers}
+
+// Users returns the list of the task named by list, so callers can read
+// and replace it without a switch of their own.
+func (t *Task) Users(list UserList) *[]int {
+	if list == Watchers {
+		return &t.Watchers
+	}
+	return &t.Assignees
+}
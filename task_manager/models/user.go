@@ -0,0 +1,42 @@
+package models
+
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
+type User struct {
+	ID           int    `json:"id"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"-"`
+	Role         Role   `json:"role"`
+	// Email is where reminders about the user's tasks are sent; optional.
+	Email string `json:"email,omitempty"`
+}
+
+// Credentials is the body accepted by the register and login endpoints.
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// UserInput is the body of POST /users and PATCH /users/:id. Fields left
+// out of a PATCH keep their current value.
+type UserInput struct {
+	Username *string `json:"username"`
+	Password *string `json:"password"`
+	Role     *Role   `json:"role"`
+	Email    *string `json:"email"`
+}
+
+// UserPage is a single page of the user listing together with the total
+// number of users.
+type UserPage struct {
+	Users  []User `json:"users"`
+	Total  int64  `json:"total"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+	Next   string `json:"next,omitempty"`
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Workflow lists the statuses a task may be created with, the statuses in
+// which it no longer needs work and, for every status, the statuses it may
+// move to next.
+type Workflow struct {
+	Initial []status `json:"initial"`
+	// Closed statuses end the work on a task: they release the tasks it
+	// blocks, stop it from being overdue or reminded of, and let a
+	// recurring series move on.
+	Closed      []status            `json:"closed"`
+	Transitions map[status][]status `json:"transitions"`
+}
+
+// DefaultWorkflow is Pending → InProgress → Blocked → Completed → Archived
+// with the usual ways back (unblocking, reopening). A blocked task may be
+// completed directly once its blockers are done. Archived is terminal.
+func DefaultWorkflow() Workflow {
+	return Workflow{
+		Initial: []status{Pending, InProgress, Blocked, Completed},
+		Closed:  []status{Completed, Archived},
+		Transitions: map[status][]status{
+			Pending:    {InProgress, Blocked, Completed},
+			InProgress: {Pending, Blocked, Completed},
+			Blocked:    {Pending, InProgress, Completed},
+			Completed:  {Pending, InProgress, Archived},
+			Archived:   {},
+		},
+	}
+}
+
+// Validate checks that every status the workflow mentions has an entry in
+// the transition table, so no task can end up in a status it can't leave
+// through the table.
+func (w Workflow) Validate() error {
+	if len(w.Initial) == 0 {
+		return fmt.Errorf("workflow needs at least one initial status")
+	}
+	for _, s := range w.Initial {
+		if !w.IsKnown(s) {
+			return fmt.Errorf("initial status %q has no transitions entry", s)
+		}
+	}
+	if len(w.Closed) == 0 {
+		return fmt.Errorf("workflow needs at least one closed status")
+	}
+	for _, s := range w.Closed {
+		if !w.IsKnown(s) {
+			return fmt.Errorf("closed status %q has no transitions entry", s)
+		}
+	}
+	for from, targets := range w.Transitions {
+		if from == "" {
+			return fmt.Errorf("workflow contains an empty status")
+		}
+		for _, to := range targets {
+			if !w.IsKnown(to) {
+				return fmt.Errorf("status %q can move to %q, which has no transitions entry", from, to)
+			}
+		}
+	}
+	return nil
+}
+
+func (w Workflow) IsKnown(s status) bool {
+	_, ok := w.Transitions[s]
+	return ok
+}
+
+func (w Workflow) CanStartIn(s status) bool {
+	return slices.Contains(w.Initial, s)
+}
+
+func (w Workflow) CanTransition(from, to status) bool {
+	return from == to || slices.Contains(w.Transitions[from], to)
+}
+
+// Statuses returns every known status in a stable order for error messages.
+func (w Workflow) Statuses() []status {
+	statuses := make([]status, 0, len(w.Transitions))
+	for s := range w.Transitions {
+		statuses = append(statuses, s)
+	}
+	slices.Sort(statuses)
+	return statuses
+}
+
+// Next returns the statuses a task in status s may move to.
+func (w Workflow) Next(s status) []status {
+	return w.Transitions[s]
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultWorkflowTransitions(t *testing.T) {
+	w := DefaultWorkflow()
+	if err := w.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	allowed := map[status][]status{
+		Pending:    {InProgress, Blocked, Completed},
+		InProgress: {Pending, Blocked, Completed},
+		Blocked:    {Pending, InProgress, Completed},
+		Completed:  {Pending, InProgress, Archived},
+		Archived:   {},
+	}
+	all := []status{Pending, InProgress, Blocked, Completed, Archived}
+	for _, from := range all {
+		for _, to := range all {
+			want := from == to
+			for _, s := range allowed[from] {
+				want = want || s == to
+			}
+			if got := w.CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+
+	for _, s := range all {
+		if got, want := w.CanStartIn(s), s != Archived; got != want {
+			t.Errorf("CanStartIn(%s) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestWorkflowValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(w *Workflow)
+		err    string
+	}{
+		{"default", func(w *Workflow) {}, ""},
+		{"no initial status", func(w *Workflow) { w.Initial = nil }, "at least one initial status"},
+		{"unknown initial status", func(w *Workflow) { w.Initial = append(w.Initial, "Draft") }, `initial status "Draft"`},
+		{"no closed status", func(w *Workflow) { w.Closed = nil }, "at least one closed status"},
+		{"unknown closed status", func(w *Workflow) { w.Closed = append(w.Closed, "Done") }, `closed status "Done"`},
+		{"unknown target", func(w *Workflow) { w.Transitions[Archived] = []status{"Gone"} }, `can move to "Gone"`},
+		{"empty status", func(w *Workflow) { w.Transitions[""] = nil }, "empty status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := DefaultWorkflow()
+			tt.change(&w)
+			err := w.Validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("got %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"task_manager/config"
+	"time"
+)
+
+// emailTimeout bounds a single SMTP session, from dialing to QUIT.
+const emailTimeout = 10 * time.Second
+
+// EmailChannel mails reminders to the owner, assignees and watchers of a
+// task who have an email address.
+type EmailChannel struct {
+	addr string
+	auth smtp.Auth
+	from mail.Address
+}
+
+// NewEmailChannel sends through the SMTP server at addr, logging in with
+// username and password unless username is empty.
+func NewEmailChannel(addr, username, password, from string) *EmailChannel {
+	channel := &EmailChannel{addr: addr}
+	if parsed, err := mail.ParseAddress(from); err == nil {
+		channel.from = *parsed
+	}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		channel.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return channel
+}
+
+func (e *EmailChannel) Name() string { return config.EmailChannel }
+
+// Send mails one message to all recipients. The SMTP session ends after
+// emailTimeout or when ctx is cancelled, whichever comes first.
+func (e *EmailChannel) Send(ctx context.Context, reminder Reminder) error {
+	var to []string
+	for _, user := range reminder.Recipients {
+		if user.Email != "" {
+			to = append(to, user.Email)
+		}
+	}
+	if len(to) == 0 {
+		return ErrNoRecipients
+	}
+	task := reminder.Task
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "Reminder: "+task.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <reminder-%d@%s>\r\n", reminder.Delivery.ID, e.domain())
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	fmt.Fprintf(&msg, "%s.\r\n", describe(reminder))
+	if task.Description != "" {
+		fmt.Fprintf(&msg, "\r\n%s\r\n", strings.ReplaceAll(strings.ReplaceAll(task.Description, "\r\n", "\n"), "\n", "\r\n"))
+	}
+
+	return e.send(ctx, to, msg.Bytes())
+}
+
+// send does what smtp.SendMail does, over a connection that is closed when
+// ctx is cancelled or emailTimeout passes, so a stalled server can not hold
+// up the dispatcher.
+func (e *EmailChannel) send(ctx context.Context, to []string, msg []byte) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, emailTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer func() {
+		// a session cut short by ctx fails with a closed connection error;
+		// report why it was closed instead
+		if !stop() && err != nil {
+			err = ctx.Err()
+		}
+	}()
+
+	host, _, _ := net.SplitHostPort(e.addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Hello("localhost"); err != nil {
+		return err
+	}
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := client.Auth(e.auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(e.from.Address); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// domain returns the domain of the sender address, which names the
+// messages it sends.
+func (e *EmailChannel) domain() string {
+	if at := strings.LastIndex(e.from.Address, "@"); at >= 0 {
+		return e.from.Address[at+1:]
+	}
+	return "localhost"
+}
@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"strings"
+	"task_manager/models"
+	"testing"
+	"time"
+)
+
+// smtpServer is a minimal SMTP server that records the last message, or
+// never answers if stalled.
+type smtpServer struct {
+	addr       string
+	recipients chan []string
+	messages   chan string
+}
+
+func newSMTPServer(t *testing.T, stalled bool) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &smtpServer{addr: listener.Addr().String(), recipients: make(chan []string, 1), messages: make(chan string, 1)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+			if !stalled {
+				go s.serve(textproto.NewConn(conn))
+			}
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn *textproto.Conn) {
+	defer conn.Close()
+	var to []string
+	conn.PrintfLine("220 localhost ready")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		switch verb, _, _ := strings.Cut(line, " "); strings.ToUpper(verb) {
+		case "EHLO", "HELO", "MAIL":
+			conn.PrintfLine("250 OK")
+		case "RCPT":
+			to = append(to, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			conn.PrintfLine("250 OK")
+		case "DATA":
+			conn.PrintfLine("354 Go ahead")
+			body, err := conn.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.recipients <- to
+			s.messages <- string(body)
+			conn.PrintfLine("250 Queued")
+		case "QUIT":
+			conn.PrintfLine("221 Bye")
+			return
+		default:
+			conn.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+func emailReminder() Reminder {
+	return Reminder{
+		Delivery: models.ReminderDelivery{ID: 7},
+		Task:     models.Task{ID: 3, Title: "Pay rent", Description: "Before noon", DueDate: time.Now().Add(time.Hour)},
+		Recipients: []models.User{
+			{ID: 1, Username: "ann", Email: "ann@example.com"},
+			{ID: 2, Username: "bob"},
+			{ID: 3, Username: "cid", Email: "cid@example.com"},
+		},
+	}
+}
+
+func TestEmailChannelSend(t *testing.T) {
+	server := newSMTPServer(t, false)
+	channel := NewEmailChannel(server.addr, "", "", "Tasks <tasks@example.com>")
+
+	if err := channel.Send(context.Background(), emailReminder()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(<-server.recipients, ","), "ann@example.com,cid@example.com"; got != want {
+		t.Errorf("recipients %q, want %q", got, want)
+	}
+	msg := <-server.messages
+	for _, want := range []string{"Subject: Reminder: Pay rent", "Message-ID: <reminder-7@example.com>", "Before noon"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message lacks %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestEmailChannelSendStopsWithContext(t *testing.T) {
+	server := newSMTPServer(t, true)
+	channel := NewEmailChannel(server.addr, "", "", "tasks@example.com")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := channel.Send(ctx, emailReminder())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > emailTimeout/2 {
+		t.Errorf("Send returned after %s", elapsed)
+	}
+}
@@ -0,0 +1,22 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"task_manager/config"
+)
+
+// LogChannel writes reminders to the server log, which is mostly useful
+// while setting up the other channels.
+type LogChannel struct{}
+
+func NewLogChannel() *LogChannel {
+	return &LogChannel{}
+}
+
+func (LogChannel) Name() string { return config.LogChannel }
+
+func (LogChannel) Send(ctx context.Context, reminder Reminder) error {
+	log.Printf("Reminder %d: %s", reminder.Delivery.ID, describe(reminder))
+	return nil
+}
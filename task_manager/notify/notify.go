@@ -0,0 +1,99 @@
+// Package notify sends task reminders through the configured channels.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"task_manager/config"
+	"task_manager/models"
+)
+
+// Reminder is what a channel sends: a delivery together with its task and
+// the people the task concerns.
+type Reminder struct {
+	Delivery models.ReminderDelivery
+	Task     models.Task
+	// Recipients are the owner, assignees and watchers of Task that still
+	// exist.
+	Recipients []models.User
+}
+
+// Channel is a way of sending reminders.
+type Channel interface {
+	Name() string
+	// Send delivers the reminder once. An error wrapped by Permanent means
+	// retrying can not help; ErrNoRecipients means there was nobody to
+	// send it to.
+	Send(ctx context.Context, reminder Reminder) error
+}
+
+// ErrNoRecipients is returned by channels that address people when none of
+// the recipients can be reached through them.
+var ErrNoRecipients = errors.New("no recipient can be reached through the channel")
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as one that trying again would not fix.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked by Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// NewChannels builds the channels enabled in cfg, in the order listed.
+func NewChannels(cfg config.Reminders) ([]Channel, error) {
+	channels := make([]Channel, 0, len(cfg.Channels))
+	for _, name := range cfg.Channels {
+		switch name {
+		case config.LogChannel:
+			channels = append(channels, NewLogChannel())
+		case config.WebhookChannel:
+			channels = append(channels, NewWebhookChannel(cfg.WebhookURL, cfg.WebhookSecret))
+		case config.EmailChannel:
+			channels = append(channels, NewEmailChannel(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom))
+		default:
+			return nil, fmt.Errorf("unknown reminder channel %q", name)
+		}
+	}
+	return channels, nil
+}
+
+// describe returns a one-line description of the reminder such as
+// "Task 4 \"Write report\" is due in 30m, at 2025-05-01 17:00 UTC".
+func describe(reminder Reminder) string {
+	task := reminder.Task
+	when := "is due now"
+	switch offset := reminder.Delivery.Offset; {
+	case offset > 0:
+		when = fmt.Sprintf("is due in %s", minutes(offset))
+	case offset < 0:
+		when = fmt.Sprintf("was due %s ago", minutes(-offset))
+	}
+	return fmt.Sprintf("Task %d %q %s, at %s", task.ID, task.Title, when, task.DueDate.UTC().Format("2006-01-02 15:04 MST"))
+}
+
+// minutes formats a number of minutes like "2d 3h 15m".
+func minutes(n int) string {
+	days, hours, mins := n/(24*60), n/60%24, n%60
+	switch {
+	case days > 0 && hours == 0 && mins == 0:
+		return fmt.Sprintf("%dd", days)
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, mins)
+	case hours > 0 && mins == 0:
+		return fmt.Sprintf("%dh", hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, mins)
+	}
+	return fmt.Sprintf("%dm", mins)
+}
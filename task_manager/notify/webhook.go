@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"task_manager/config"
+	"task_manager/models"
+	"time"
+)
+
+// webhookTimeout bounds a single webhook request.
+const webhookTimeout = 10 * time.Second
+
+// WebhookChannel posts reminders as JSON to a URL. Every request is signed
+// so the receiver can tell it came from this server:
+//
+//	X-Webhook-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+//
+// where timestamp is the X-Webhook-Timestamp header, in Unix seconds.
+// X-Webhook-Id carries the delivery ID, which stays the same when a
+// delivery is retried, so receivers can drop repeats.
+type WebhookChannel struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func NewWebhookChannel(url, secret string) *WebhookChannel {
+	return &WebhookChannel{url: url, secret: []byte(secret), client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// webhookPayload is the body of a webhook request.
+type webhookPayload struct {
+	Event      string      `json:"event"`
+	DeliveryID int         `json:"delivery_id"`
+	Offset     int         `json:"offset"`
+	RemindAt   time.Time   `json:"remind_at"`
+	Task       models.Task `json:"task"`
+}
+
+func (w *WebhookChannel) Name() string { return config.WebhookChannel }
+
+// Send posts the reminder and succeeds on any 2xx response. Other client
+// errors are permanent, except 408 and 429, which ask to come back later.
+func (w *WebhookChannel) Send(ctx context.Context, reminder Reminder) error {
+	body, err := json.Marshal(webhookPayload{
+		Event:      "task.reminder",
+		DeliveryID: reminder.Delivery.ID,
+		Offset:     reminder.Delivery.Offset,
+		RemindAt:   reminder.Delivery.RemindAt(),
+		Task:       reminder.Task,
+	})
+	if err != nil {
+		return Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", strconv.Itoa(reminder.Delivery.ID))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+w.sign(timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("webhook answered %s", resp.Status)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
+
+func (w *WebhookChannel) sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, w.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
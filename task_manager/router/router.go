@@ -1,19 +1,66 @@
 package router
 
 import (
+	"task_manager/auth"
 	task_controllers "task_manager/controllers"
+	"task_manager/data"
+	"task_manager/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func InitRouter() *gin.Engine{
+func InitRouter(taskController *task_controllers.TaskController, userController *task_controllers.UserController, commentController *task_controllers.CommentController, attachmentController *task_controllers.AttachmentController, reminderController *task_controllers.ReminderController, healthController *task_controllers.HealthController, tokens *auth.TokenService, accounts data.UserRepository) *gin.Engine{
 	router := gin.Default()
 
-	router.GET("/tasks", task_controllers.GetTasks)
-	router.GET("/tasks/:id", task_controllers.GetATask)
-	router.PUT("/tasks/:id", task_controllers.UpdateATask)
-	router.DELETE("/tasks/:id", task_controllers.DeleteATask)
-	router.POST("/tasks", task_controllers.PostTask)
+	router.GET("/healthz", healthController.Live)
+	router.GET("/readyz", healthController.Ready)
+
+	router.POST("/register", userController.Register)
+	router.POST("/login", userController.Login)
+
+	tasks := router.Group("/tasks", middleware.RequireAuth(tokens, accounts))
+	tasks.GET("", taskController.GetTasks)
+	tasks.POST("/bulk", taskController.BulkTasks)
+	tasks.GET("/export", taskController.ExportTasks)
+	tasks.POST("/import", taskController.ImportTasks)
+	tasks.GET("/overdue", taskController.GetOverdueTasks)
+	tasks.GET("/search", taskController.SearchTasks)
+	tasks.GET("/tags", taskController.GetTags)
+	tasks.GET("/trash", taskController.GetTrash)
+	tasks.GET("/:id", taskController.GetATask)
+	tasks.PUT("/:id", taskController.UpdateATask)
+	tasks.PATCH("/:id", taskController.PatchATask)
+	tasks.POST("/:id/transition", taskController.TransitionTask)
+	tasks.POST("/:id/restore", taskController.RestoreDeletedTask)
+	tasks.POST("/:id/tags", taskController.AddTags)
+	tasks.DELETE("/:id/tags/:tag", taskController.RemoveTag)
+	tasks.POST("/:id/assignees", taskController.AddAssignees)
+	tasks.DELETE("/:id/assignees/:user_id", taskController.RemoveAssignee)
+	tasks.POST("/:id/watchers", taskController.AddWatchers)
+	tasks.DELETE("/:id/watchers/:user_id", taskController.RemoveWatcher)
+	tasks.GET("/:id/comments", commentController.GetComments)
+	tasks.POST("/:id/comments", commentController.PostComment)
+	tasks.GET("/:id/comments/:comment_id", commentController.GetComment)
+	tasks.PUT("/:id/comments/:comment_id", commentController.UpdateComment)
+	tasks.DELETE("/:id/comments/:comment_id", commentController.DeleteComment)
+	tasks.GET("/:id/attachments", attachmentController.GetAttachments)
+	tasks.POST("/:id/attachments", attachmentController.UploadAttachment)
+	tasks.GET("/:id/attachments/:attachment_id", attachmentController.DownloadAttachment)
+	tasks.DELETE("/:id/attachments/:attachment_id", attachmentController.DeleteAttachment)
+	tasks.GET("/:id/reminders", reminderController.GetReminders)
+	tasks.GET("/:id/graph", taskController.GetTaskGraph)
+	tasks.GET("/:id/history", taskController.GetTaskHistory)
+	tasks.POST("/:id/history/:version/restore", taskController.RestoreTask)
+	tasks.DELETE("/:id", taskController.DeleteATask)
+	tasks.POST("", taskController.PostTask)
+
+	users := router.Group("/users", middleware.RequireAuth(tokens, accounts))
+	users.GET("", userController.ListUsers)
+	users.POST("", userController.CreateUser)
+	users.GET("/:id", userController.GetUser)
+	users.PATCH("/:id", userController.UpdateUser)
+	users.DELETE("/:id", userController.DeleteUser)
+	users.GET("/:id/tasks", userController.GetUserTasks)
 
 	return router 
 }
\ No newline at end of file